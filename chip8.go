@@ -3,12 +3,26 @@ package main
 import (
 	"chip8"
 	"flag"
+	"github.com/veandco/go-sdl2/sdl"
 	"io/ioutil"
 	"os"
+	"os/signal"
 )
 
 func main() {
 	var romFile = flag.String("rom", "", "The filename of the Chip8 ROM you want to execute")
+	var platform = flag.String("platform", "vip", "The quirks to emulate: vip, chip48, schip or xochip")
+	var skipUnknown = flag.Bool("skip-unknown", false, "Treat unknown opcodes as NOPs rather than stopping")
+	var trace = flag.String("trace", "off", "Trace every executed instruction to stdout: off, text or json")
+	var traceLevel = flag.String("trace-level", "all", "Instructions to trace: all, or flow for jumps, calls, returns and skips")
+	var mute = flag.Bool("mute", false, "Run without sound")
+	var debug = flag.Bool("debug", false, "Start in the interactive debugger, reading commands from stdin")
+	var seed = flag.Int64("seed", 0, "Seed for the random numbers, to make runs repeatable. Defaults to a different seed every run")
+	var randomName = flag.String("random", "pseudo", "The random number generator: pseudo, or table for one that repeats much sooner")
+	var recordFile = flag.String("record", "", "Record the keys pressed to a movie file, to replay the run exactly with -replay")
+	var replayFile = flag.String("replay", "", "Replay a movie file recorded with -record")
+	var rewindMegabytes = flag.Int("rewind", 16, "Megabytes of memory for rewinding with backspace, or 0 to turn rewinding off")
+	var instructionsPerFrame = flag.Int("ipf", 0, "Instructions executed per 60Hz frame, defaults to the usual speed for the platform")
 	flag.Parse()
 
 	if *romFile == "" {
@@ -16,70 +30,231 @@ func main() {
 		os.Exit(1)
 	}
 
+	quirks, ok := chip8.QuirksByName(*platform)
+	if !ok {
+		println("Unknown platform", *platform)
+		os.Exit(1)
+	}
+
+	level, ok := chip8.TraceLevelByName(*traceLevel)
+	if !ok {
+		println("Unknown trace level", *traceLevel)
+		os.Exit(1)
+	}
+	tracer, ok := chip8.TracerByName(*trace, level, os.Stdout)
+	if !ok {
+		println("Unknown trace format", *trace)
+		os.Exit(1)
+	}
+
+	var movie *chip8.Movie
+	if *replayFile != "" {
+		var err error
+		if movie, err = readMovie(*replayFile); err != nil {
+			println("Unable to read movie:", err.Error())
+			os.Exit(1)
+		}
+		quirks = movie.Quirks
+		*instructionsPerFrame = movie.InstructionsPerFrame
+		*randomName = movie.Random
+		*seed = movie.Seed
+	} else if !flagSet("seed") {
+		*seed = chip8.NewSeed()
+	}
+
+	random, ok := chip8.RandomByName(*randomName, *seed)
+	if !ok {
+		println("Unknown random generator", *randomName)
+		os.Exit(1)
+	}
+
 	chip8Display := Chip8Display{}
 	defer chip8Display.shutdown()
 	chip8Display.startUp()
 
-	random := chip8.NewRandom()
+	var display chip8.DisplayInterface = &chip8Display
+	var recorder *chip8.MovieRecorder
+	if movie != nil {
+		display = chip8.NewMoviePlayer(display, movie)
+	} else if *recordFile != "" {
+		recorder = chip8.NewMovieRecorder(display)
+		display = recorder
+	}
+
+	vm := chip8.NewVM(display, random, quirks)
+	if *instructionsPerFrame == 0 {
+		*instructionsPerFrame, _ = chip8.InstructionsPerFrameByName(*platform)
+	}
+	vm.SetInstructionsPerFrame(*instructionsPerFrame)
+	vm.SetTracer(tracer)
+	if *skipUnknown {
+		vm.SetUnknownOpcodePolicy(chip8.SkipUnknownOpcode)
+	}
 
-	vm := chip8.NewVM(&chip8Display, random)
+	if !*mute {
+		chip8Audio := Chip8Audio{}
+		if err := chip8Audio.startUp(); err != nil {
+			println("Unable to open audio device, running without sound:", err.Error())
+		} else {
+			defer chip8Audio.shutdown()
+			vm.SetAudio(chip8.NewSampleAudio(&chip8Audio, sampleRate))
+		}
+	}
 
 	dat, _ := ioutil.ReadFile(*romFile)
 	//check(err)
 
 	vm.Load(dat)
+	if movie != nil {
+		if err := movie.Check(vm); err != nil {
+			println(err.Error())
+			os.Exit(1)
+		}
+	}
+	if recorder != nil {
+		defer func() { writeMovie(recorder.Movie(vm, *randomName, *seed), *recordFile) }()
+	}
+
+	// Loading a state or rewinding would make the movie different from the run
+	stateFile := *romFile + ".state"
+	chip8Display.SetHotkey(sdl.K_F5, func() { saveState(vm, stateFile) })
+	if movie == nil && recorder == nil {
+		chip8Display.SetHotkey(sdl.K_F9, func() { loadState(vm, stateFile) })
+	}
+
+	if *rewindMegabytes > 0 && movie == nil && recorder == nil {
+		rewind := chip8.NewRewind(vm, *rewindMegabytes<<20)
+		vm.SetRewind(rewind)
+		chip8Display.SetHoldKey(sdl.K_BACKSPACE, rewind.SetRewinding)
+	}
 
 	//vm.Load(testOpcode())
-	vm.Run()
+	if *debug {
+		debugger := chip8.NewDebugger(vm, os.Stdin, os.Stdout)
+		// Ctrl-C stops continue rather than the emulator
+		interrupts := make(chan os.Signal, 1)
+		signal.Notify(interrupts, os.Interrupt)
+		go func() {
+			for range interrupts {
+				debugger.Interrupt()
+			}
+		}()
+		if err := debugger.Run(); err != nil {
+			println(err.Error())
+		}
+		return
+	}
+	if err := vm.Run(); err != nil {
+		chip8Display.showCrash(err)
+	}
 	//Chip8Display.ClearScreen()
 }
 
+// saveState writes the state of the VM to filename, for F5
+func saveState(vm *chip8.VM, filename string) {
+	file, err := os.Create(filename)
+	if err == nil {
+		err = vm.SaveState(file)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		println("Unable to save state:", err.Error())
+		return
+	}
+	println("Saved state to", filename)
+}
+
+// loadState restores the state of the VM from filename, for F9
+func loadState(vm *chip8.VM, filename string) {
+	file, err := os.Open(filename)
+	if err == nil {
+		err = vm.LoadState(file)
+		file.Close()
+	}
+	if err != nil {
+		println("Unable to load state:", err.Error())
+		return
+	}
+	println("Loaded state from", filename)
+}
+
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func readMovie(filename string) (*chip8.Movie, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return chip8.ReadMovie(file)
+}
+
+// writeMovie saves what was recorded with -record
+func writeMovie(movie *chip8.Movie, filename string) {
+	file, err := os.Create(filename)
+	if err == nil {
+		_, err = movie.WriteTo(file)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		println("Unable to write movie:", err.Error())
+	}
+}
+
 const x0 = 0x08
 const x1 = 0x09
 const x2 = 0x0A
 const y = 0x0B
 
-func testOpcode() []byte {
+func testOpcode() ([]byte, error) {
 	a := chip8.NewAssembler()
 
-	// programs start at 0x200
-	const start = 0x200
-	address := start
-
-	a.Jump(0x248)
-	address += 2
-
-	imageok, address := data(address, a, []byte{0xEA, 0xAC, 0xAA, 0xEA})
-	imagefalse, address := data(address, a, []byte{0xCE, 0xAA, 0xAA, 0xAE})
-	_, address = data(address, a, []byte{0xE0, 0xA0, 0xA0, 0xE0})
-	_, address = data(address, a, []byte{0xC0, 0x40, 0x40, 0xE0})
-	_, address = data(address, a, []byte{0xE0, 0x20, 0xC0, 0xE0})
-	im3, address := data(address, a, []byte{0xE0, 0x60, 0x20, 0xE0})
-	im4, address := data(address, a, []byte{0xA0, 0xE0, 0x20, 0x20})
-	im5, address := data(address, a, []byte{0x60, 0x40, 0x20, 0x40})
-	_, address = data(address, a, []byte{0xE0, 0x80, 0xE0, 0xE0})
-	im7, address := data(address, a, []byte{0xE0, 0x20, 0x20, 0x20})
-	_, address = data(address, a, []byte{0xE0, 0xE0, 0xA0, 0xE0})
-	im9, address := data(address, a, []byte{0xE0, 0xE0, 0x20, 0xE0})
-	imA, address := data(address, a, []byte{0x40, 0xA0, 0xE0, 0xA0})
-	_, address = data(address, a, []byte{0xE0, 0xC0, 0x80, 0xE0})
-	_, address = data(address, a, []byte{0xE0, 0x80, 0xC0, 0x80})
-	imX, address := data(address, a, []byte{0xA0, 0x40, 0xA0, 0xA0})
-
-	// testAX
-	// 0x242
-	println("testAX = ", address)
-	testAX := address
-	a.SetIndexRegister(uint16(imageok))
-	address += 2
+	a.JumpTo("main")
+
+	a.Label("imageok")
+	a.Data([]byte{0xEA, 0xAC, 0xAA, 0xEA})
+	a.Label("imagefalse")
+	a.Data([]byte{0xCE, 0xAA, 0xAA, 0xAE})
+	a.Data([]byte{0xE0, 0xA0, 0xA0, 0xE0})
+	a.Data([]byte{0xC0, 0x40, 0x40, 0xE0})
+	a.Data([]byte{0xE0, 0x20, 0xC0, 0xE0})
+	a.Label("im3")
+	a.Data([]byte{0xE0, 0x60, 0x20, 0xE0})
+	a.Label("im4")
+	a.Data([]byte{0xA0, 0xE0, 0x20, 0x20})
+	a.Label("im5")
+	a.Data([]byte{0x60, 0x40, 0x20, 0x40})
+	a.Data([]byte{0xE0, 0x80, 0xE0, 0xE0})
+	a.Label("im7")
+	a.Data([]byte{0xE0, 0x20, 0x20, 0x20})
+	a.Data([]byte{0xE0, 0xE0, 0xA0, 0xE0})
+	a.Label("im9")
+	a.Data([]byte{0xE0, 0xE0, 0x20, 0xE0})
+	a.Label("imA")
+	a.Data([]byte{0x40, 0xA0, 0xE0, 0xA0})
+	a.Data([]byte{0xE0, 0xC0, 0x80, 0xE0})
+	a.Data([]byte{0xE0, 0x80, 0xC0, 0x80})
+	a.Label("imX")
+	a.Data([]byte{0xA0, 0x40, 0xA0, 0xA0})
+
+	a.Label("testAX")
+	a.SetIndexToLabel("imageok")
 	a.Display(x2, y, 4)
-	address += 2
 	a.Return()
-	address += 2
 
-	// MAIN
-	// 0x248
-	println("main = ", address)
+	a.Label("main")
 	a.SetRegister(x0, 1)
 	a.SetRegister(x1, 5)
 	a.SetRegister(x2, 10)
@@ -87,63 +262,50 @@ func testOpcode() []byte {
 	a.SetRegister(0x05, 42)
 	a.SetRegister(0x06, 43)
 
-	drawop(a, im3, imX)
+	drawop(a, "im3", "imX")
 
-	a.SetIndexRegister(uint16(imageok))
+	a.SetIndexToLabel("imageok")
 	a.SkipIfEqual(0x06, 43)
-	a.SetIndexRegister(uint16(imagefalse))
+	a.SetIndexToLabel("imagefalse")
 	a.Display(x2, y, 4)
 
 	//test 4x
 	a.SetRegister(y, 6)
-	drawop(a, im4, imX)
-	a.SetIndexRegister(uint16(imagefalse))
+	drawop(a, "im4", "imX")
+	a.SetIndexToLabel("imagefalse")
 	a.SkipIfNotEqual(0x05, 42)
-	a.SetIndexRegister(uint16(imageok))
+	a.SetIndexToLabel("imageok")
 	a.Display(x2, y, 4)
 
 	//test 5x
 	a.SetRegister(y, 11)
-	drawop(a, im5, imX)
-	a.SetIndexRegister(uint16(imagefalse))
+	drawop(a, "im5", "imX")
+	a.SetIndexToLabel("imagefalse")
 	a.SkipIfRegistersEqual(0x05, 0x06)
-	a.SetIndexRegister(uint16(imageok))
+	a.SetIndexToLabel("imageok")
 	a.Display(x2, y, 4)
 
 	//test 7x
 	a.SetRegister(y, 16)
-	drawop(a, im7, imX)
-	a.SetIndexRegister(uint16(imagefalse))
+	drawop(a, "im7", "imX")
+	a.SetIndexToLabel("imagefalse")
 	a.AddToRegister(0x06, 255)
 	a.SkipIfNotEqual(0x06, 42)
-	a.SetIndexRegister(uint16(imageok))
+	a.SetIndexToLabel("imageok")
 	a.Display(x2, y, 4)
 
 	//test 9x
 	a.SetRegister(y, 21)
-	drawop(a, im9, imX)
-	a.SetIndexRegister(uint16(imagefalse))
+	drawop(a, "im9", "imX")
+	a.SetIndexToLabel("imagefalse")
 	a.SkipIfRegistersNotEqual(0x05, 0x06)
-	a.SetIndexRegister(uint16(imageok))
+	a.SetIndexToLabel("imageok")
 	a.Display(x2, y, 4)
 
 	// test AX
 	a.SetRegister(y, 26)
-	drawop(a, imA, imX)
-	a.Sub(uint16(testAX))
-
-	//test 0E
-	//a.SetRegister(x0, 2)
-	//a.SetRegister(x1, 2)
-	//a.SetRegister(x2, 32)
-	//a.SetRegister(y, 10)
-	//
-	//drawop(a, im0, imE)
-	//// drawop im0 imE
-	//a.SetIndexRegister(uint16(im0))
-	//a.Display(x0, y, 4)
-	//a.SetIndexRegister(uint16(imE))
-	//a.Display(x1, y, 4)
+	drawop(a, "imA", "imX")
+	a.CallLabel("testAX")
 
 	a.GetKey(3)
 	a.GetKey(3)
@@ -151,21 +313,14 @@ func testOpcode() []byte {
 	return a.Assemble()
 }
 
-func drawop(a *chip8.Assembler, im3 int, imX int) {
-	a.SetIndexRegister(uint16(im3))
+func drawop(a *chip8.Assembler, im3 string, imX string) {
+	a.SetIndexToLabel(im3)
 	a.Display(byte(x0), byte(y), 4)
-	a.SetIndexRegister(uint16(imX))
+	a.SetIndexToLabel(imX)
 	a.Display(byte(x1), byte(y), 4)
 }
 
-func data(address int, a *chip8.Assembler, bytes []byte) (int, int) {
-	im3 := address
-	a.Data(bytes)
-	address += len(bytes)
-	return im3, address
-}
-
-func andysProgram() []byte {
+func andysProgram() ([]byte, error) {
 	a := chip8.NewAssembler()
 
 	a.ClearScreen()
@@ -0,0 +1,307 @@
+package chip8
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// maxJumpTableSize is how far BNNN can reach past NNN, as V0 is at most 255.
+const maxJumpTableSize = 0x100
+
+// Analysis separates a program into code and data by following every path through it from the
+// origin, and groups the code into basic blocks and subroutines.
+type Analysis struct {
+	Origin      uint16
+	Blocks      []*BasicBlock
+	Subroutines []*Subroutine
+
+	code         []byte
+	instructions map[int]int
+	targets      map[int]bool
+}
+
+// BasicBlock is a run of instructions that is only entered at the start and only left at the end.
+type BasicBlock struct {
+	Start uint16
+	// End is the address after the last instruction of the block.
+	End        uint16
+	Successors []uint16
+	Calls      []uint16
+}
+
+// Subroutine is the code reachable from the origin or from the target of a 2NNN, without returning.
+type Subroutine struct {
+	Entry  uint16
+	Blocks []uint16
+	Calls  []uint16
+}
+
+// Analyze follows 1NNN jumps, 2NNN calls, 00EE returns, skips and BNNN jump tables from origin.
+func Analyze(code []byte, origin uint16) *Analysis {
+	a := &Analysis{
+		Origin:       origin,
+		code:         code,
+		instructions: make(map[int]int),
+		targets:      make(map[int]bool),
+	}
+	a.trace()
+	a.buildBlocks()
+	a.buildSubroutines()
+	return a
+}
+
+// IsCode reports whether the byte at address belongs to an instruction that can be executed.
+func (a *Analysis) IsCode(address uint16) bool {
+	for start := int(address) - 3; start <= int(address); start++ {
+		if size, ok := a.instructions[start]; ok && start+size > int(address) {
+			return true
+		}
+	}
+	return false
+}
+
+// Block returns the basic block starting at address, or nil if there isn't one.
+func (a *Analysis) Block(address uint16) *BasicBlock {
+	n := sort.Search(len(a.Blocks), func(n int) bool { return a.Blocks[n].Start >= address })
+	if n < len(a.Blocks) && a.Blocks[n].Start == address {
+		return a.Blocks[n]
+	}
+	return nil
+}
+
+func (a *Analysis) word(address int) (uint16, bool) {
+	offset := address - int(a.Origin)
+	if offset < 0 || offset+1 >= len(a.code) {
+		return 0, false
+	}
+	return bytesToWord(a.code[offset], a.code[offset+1]), true
+}
+
+// instructionAt decodes the instruction at address, returning its size or 0 if it isn't one.
+func (a *Analysis) instructionAt(address int) (uint16, *opcode, int) {
+	word, ok := a.word(address)
+	op := decode(word)
+	if !ok || op == nil || word == 0x0000 {
+		return word, nil, 0
+	}
+	if op.name == "LongSetIndexRegister" {
+		if _, ok := a.word(address + 2); !ok {
+			return word, nil, 0
+		}
+		return word, op, 4
+	}
+	return word, op, 2
+}
+
+// flow returns where execution can go after the instruction at address, and any subroutine it calls.
+func (a *Analysis) flow(address int) (next []int, calls []int) {
+	word, op, size := a.instructionAt(address)
+	following := address + size
+	target := int(word & 0x0FFF)
+	switch op.name {
+	case "Jump":
+		return []int{target}, nil
+	case "Subroutine":
+		return []int{following}, []int{target}
+	case "JumpWithOffset":
+		return a.jumpTable(target), nil
+	case "Return", "Exit":
+		return nil, nil
+	case "SkipIfEqual", "SkipIfNotEqual", "SkipIfRegistersEqual", "SkipIfRegistersNotEqual", "SkipIfKey", "SkipIfNotKey":
+		skipped := following + 2
+		if next, _ := a.word(following); next == 0xF000 {
+			skipped += 2
+		}
+		return []int{following, skipped}, nil
+	}
+	return []int{following}, nil
+}
+
+// jumpTable guesses the entries of a BNNN table: the first entry, and as many jumps as follow it.
+func (a *Analysis) jumpTable(start int) []int {
+	entries := []int{start}
+	for address := start + 2; address < start+maxJumpTableSize; address += 2 {
+		_, op, _ := a.instructionAt(address)
+		if op == nil || op.name != "Jump" {
+			break
+		}
+		entries = append(entries, address)
+	}
+	return entries
+}
+
+// trace marks every instruction that can be reached from the origin, and the addresses they refer to.
+func (a *Analysis) trace() {
+	pending := []int{int(a.Origin)}
+	for len(pending) > 0 {
+		address := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if _, seen := a.instructions[address]; seen {
+			continue
+		}
+		word, op, size := a.instructionAt(address)
+		if op == nil {
+			continue
+		}
+		a.instructions[address] = size
+
+		switch op.name {
+		case "Jump", "Subroutine", "SetIndexRegister", "JumpWithOffset":
+			a.targets[int(word&0x0FFF)] = true
+		case "LongSetIndexRegister":
+			long, _ := a.word(address + 2)
+			a.targets[int(long)] = true
+		}
+		next, calls := a.flow(address)
+		pending = append(pending, calls...)
+		pending = append(pending, next...)
+	}
+}
+
+func (a *Analysis) isLeader(address int, leaders map[int]bool) bool {
+	return leaders[address] || address == int(a.Origin)
+}
+
+func (a *Analysis) buildBlocks() {
+	leaders := make(map[int]bool)
+	for address := range a.instructions {
+		next, calls := a.flow(address)
+		for _, call := range calls {
+			leaders[call] = true
+		}
+		if len(next) != 1 || next[0] != address+a.instructions[address] {
+			for _, target := range next {
+				leaders[target] = true
+			}
+		}
+	}
+
+	for address := range a.instructions {
+		if !a.isLeader(address, leaders) {
+			continue
+		}
+		block := &BasicBlock{Start: uint16(address)}
+		current := address
+		for {
+			next, calls := a.flow(current)
+			for _, call := range calls {
+				block.Calls = appendAddress(block.Calls, call)
+			}
+			following := current + a.instructions[current]
+			_, fallsThrough := a.instructions[following]
+			if len(next) == 1 && next[0] == following && fallsThrough && !a.isLeader(following, leaders) {
+				current = following
+				continue
+			}
+			block.End = uint16(following)
+			for _, target := range next {
+				if _, ok := a.instructions[target]; ok {
+					block.Successors = appendAddress(block.Successors, target)
+				}
+			}
+			break
+		}
+		a.Blocks = append(a.Blocks, block)
+	}
+	sort.Slice(a.Blocks, func(i, j int) bool { return a.Blocks[i].Start < a.Blocks[j].Start })
+}
+
+func (a *Analysis) buildSubroutines() {
+	entries := []int{int(a.Origin)}
+	for _, block := range a.Blocks {
+		for _, call := range block.Calls {
+			if a.Block(call) != nil {
+				entries = append(entries, int(call))
+			}
+		}
+	}
+	seen := make(map[int]bool)
+	for _, entry := range entries {
+		if seen[entry] || a.Block(uint16(entry)) == nil {
+			continue
+		}
+		seen[entry] = true
+		subroutine := &Subroutine{Entry: uint16(entry)}
+		visited := make(map[uint16]bool)
+		pending := []uint16{uint16(entry)}
+		for len(pending) > 0 {
+			block := a.Block(pending[0])
+			pending = pending[1:]
+			if visited[block.Start] {
+				continue
+			}
+			visited[block.Start] = true
+			subroutine.Blocks = appendAddress(subroutine.Blocks, int(block.Start))
+			for _, call := range block.Calls {
+				if a.Block(call) != nil {
+					subroutine.Calls = appendAddress(subroutine.Calls, int(call))
+				}
+			}
+			pending = append(pending, block.Successors...)
+		}
+		sortAddresses(subroutine.Blocks)
+		sortAddresses(subroutine.Calls)
+		a.Subroutines = append(a.Subroutines, subroutine)
+	}
+	sort.Slice(a.Subroutines, func(i, j int) bool { return a.Subroutines[i].Entry < a.Subroutines[j].Entry })
+}
+
+// appendAddress adds an address to a list unless it is already there.
+func appendAddress(addresses []uint16, address int) []uint16 {
+	for _, existing := range addresses {
+		if int(existing) == address {
+			return addresses
+		}
+	}
+	return append(addresses, uint16(address))
+}
+
+func sortAddresses(addresses []uint16) {
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i] < addresses[j] })
+}
+
+// WriteControlFlowDOT writes the basic blocks as a Graphviz graph, each node listing its instructions.
+func (a *Analysis) WriteControlFlowDOT(w io.Writer) error {
+	var dot strings.Builder
+	dot.WriteString("digraph controlflow {\n\tnode [shape=box, fontname=monospace];\n")
+	d := newDisassembler(a)
+	for _, block := range a.Blocks {
+		var label strings.Builder
+		for address := int(block.Start); address < int(block.End); address += a.instructions[address] {
+			fmt.Fprintf(&label, "%03X  %s\\l", address, d.formatInstruction(address))
+		}
+		fmt.Fprintf(&dot, "\tb%03X [label=\"%s\"];\n", block.Start, label.String())
+	}
+	for _, block := range a.Blocks {
+		for _, successor := range block.Successors {
+			fmt.Fprintf(&dot, "\tb%03X -> b%03X;\n", block.Start, successor)
+		}
+		for _, call := range block.Calls {
+			if a.Block(call) != nil {
+				fmt.Fprintf(&dot, "\tb%03X -> b%03X [style=dashed];\n", block.Start, call)
+			}
+		}
+	}
+	dot.WriteString("}\n")
+	_, err := io.WriteString(w, dot.String())
+	return err
+}
+
+// WriteCallGraphDOT writes the subroutines as a Graphviz graph, with an edge for each call.
+func (a *Analysis) WriteCallGraphDOT(w io.Writer) error {
+	var dot strings.Builder
+	dot.WriteString("digraph calls {\n\tnode [shape=ellipse, fontname=monospace];\n")
+	for _, subroutine := range a.Subroutines {
+		fmt.Fprintf(&dot, "\ts%03X [label=\"L%03X\"];\n", subroutine.Entry, subroutine.Entry)
+	}
+	for _, subroutine := range a.Subroutines {
+		for _, call := range subroutine.Calls {
+			fmt.Fprintf(&dot, "\ts%03X -> s%03X;\n", subroutine.Entry, call)
+		}
+	}
+	dot.WriteString("}\n")
+	_, err := io.WriteString(w, dot.String())
+	return err
+}
@@ -0,0 +1,126 @@
+package chip8
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type AnalyzerTestSuite struct {
+	suite.Suite
+}
+
+func (suite *AnalyzerTestSuite) analyze(a *Assembler) *Analysis {
+	code, err := a.Assemble()
+	suite.Require().NoError(err)
+	return Analyze(code, ProgramStart)
+}
+
+func (suite *AnalyzerTestSuite) TestDataAfterAJumpIsNotCode() {
+	a := NewAssembler()
+	a.JumpTo("main")
+	a.Label("sprite")
+	a.Data([]byte{0x60, 0x01, 0xA2, 0x02})
+	a.Label("main")
+	a.SetIndexToLabel("sprite")
+	a.Exit()
+
+	analysis := suite.analyze(a)
+
+	suite.True(analysis.IsCode(0x200))
+	suite.True(analysis.IsCode(0x201))
+	suite.False(analysis.IsCode(0x202))
+	suite.False(analysis.IsCode(0x205))
+	suite.True(analysis.IsCode(0x206))
+	suite.True(analysis.IsCode(0x209))
+	suite.False(analysis.IsCode(0x20A))
+}
+
+func (suite *AnalyzerTestSuite) TestSkipsSplitBasicBlocks() {
+	a := NewAssembler()
+	a.Label("loop")
+	a.AddToRegister(0, 1)
+	a.SkipIfEqual(0, 10)
+	a.JumpTo("loop")
+	a.Exit()
+
+	analysis := suite.analyze(a)
+
+	suite.Len(analysis.Blocks, 3)
+	suite.Equal(&BasicBlock{Start: 0x200, End: 0x204, Successors: []uint16{0x204, 0x206}}, analysis.Blocks[0])
+	suite.Equal(&BasicBlock{Start: 0x204, End: 0x206, Successors: []uint16{0x200}}, analysis.Blocks[1])
+	suite.Equal(&BasicBlock{Start: 0x206, End: 0x208}, analysis.Blocks[2])
+}
+
+func (suite *AnalyzerTestSuite) TestCallGraph() {
+	a := NewAssembler()
+	a.CallLabel("first")
+	a.CallLabel("second")
+	a.Exit()
+	a.Label("first")
+	a.CallLabel("second")
+	a.Return()
+	a.Label("second")
+	a.Return()
+
+	analysis := suite.analyze(a)
+
+	suite.Equal([]*Subroutine{
+		{Entry: 0x200, Blocks: []uint16{0x200}, Calls: []uint16{0x206, 0x20A}},
+		{Entry: 0x206, Blocks: []uint16{0x206}, Calls: []uint16{0x20A}},
+		{Entry: 0x20A, Blocks: []uint16{0x20A}},
+	}, analysis.Subroutines)
+	suite.Equal([]uint16{0x206, 0x20A}, analysis.Block(0x200).Calls)
+}
+
+func (suite *AnalyzerTestSuite) TestJumpTableIsFollowed() {
+	a := NewAssembler()
+	a.JumpWithOffsetTo("table")
+	a.Label("table")
+	a.JumpTo("zero")
+	a.JumpTo("two")
+	a.Label("zero")
+	a.Exit()
+	a.Label("two")
+	a.Return()
+
+	analysis := suite.analyze(a)
+
+	suite.Equal([]uint16{0x202, 0x204}, analysis.Block(0x200).Successors)
+	suite.True(analysis.IsCode(0x206))
+	suite.True(analysis.IsCode(0x208))
+}
+
+func (suite *AnalyzerTestSuite) TestDOTExport() {
+	a := NewAssembler()
+	a.Label("loop")
+	a.CallLabel("draw")
+	a.JumpTo("loop")
+	a.Label("draw")
+	a.Return()
+	analysis := suite.analyze(a)
+
+	var controlFlow, calls bytes.Buffer
+	suite.NoError(analysis.WriteControlFlowDOT(&controlFlow))
+	suite.NoError(analysis.WriteCallGraphDOT(&calls))
+
+	suite.Equal(`digraph controlflow {
+	node [shape=box, fontname=monospace];
+	b200 [label="200  CALL 0x204\l202  JP 0x200\l"];
+	b204 [label="204  RET\l"];
+	b200 -> b200;
+	b200 -> b204 [style=dashed];
+}
+`, controlFlow.String())
+	suite.Equal(`digraph calls {
+	node [shape=ellipse, fontname=monospace];
+	s200 [label="L200"];
+	s204 [label="L204"];
+	s200 -> s204;
+}
+`, calls.String())
+}
+
+func TestAnalyzerSuite(t *testing.T) {
+	suite.Run(t, new(AnalyzerTestSuite))
+}
@@ -1,25 +1,114 @@
 package chip8
 
+import "fmt"
+
+const maxAddress = 0xFFF
+
 type Assembler struct {
-	code []byte
+	code   []byte
+	labels map[string]uint16
+	fixups []labelFixup
+	err    error
+}
+
+// labelFixup is an instruction whose address is filled in from a label once the program is assembled.
+type labelFixup struct {
+	offset int
+	label  string
 }
 
 func NewAssembler() *Assembler {
 	a := new(Assembler)
 	a.code = make([]byte, 0)
+	a.labels = make(map[string]uint16)
 	return a
 }
 
+// Label names the address of the next instruction, for use by JumpTo, CallLabel, SetIndexToLabel
+// and JumpWithOffsetTo. Labels can be used before they are defined.
+func (a *Assembler) Label(name string) {
+	if _, ok := a.labels[name]; ok {
+		a.fail(fmt.Errorf("label %s is defined more than once", name))
+		return
+	}
+	a.labels[name] = a.address()
+}
+
+func (a *Assembler) JumpTo(label string) {
+	a.addressInstruction(0x10, label)
+}
+
+func (a *Assembler) CallLabel(label string) {
+	a.addressInstruction(0x20, label)
+}
+
+func (a *Assembler) SetIndexToLabel(label string) {
+	a.addressInstruction(0xA0, label)
+}
+
+func (a *Assembler) JumpWithOffsetTo(label string) {
+	a.addressInstruction(0xB0, label)
+}
+
+func (a *Assembler) addressInstruction(instruction byte, label string) {
+	a.fixups = append(a.fixups, labelFixup{offset: len(a.code), label: label})
+	a.buildArray(instruction, 0x00)
+}
+
+// address is where the next instruction will be once the program is loaded.
+func (a *Assembler) address() uint16 {
+	return uint16(ProgramStart + len(a.code))
+}
+
+func (a *Assembler) checkAddress(address uint16) {
+	if address > maxAddress {
+		a.fail(fmt.Errorf("address 0x%X does not fit in 12 bits", address))
+	}
+}
+
+// fail keeps the first error, which Assemble returns.
+func (a *Assembler) fail(err error) {
+	if a.err == nil {
+		a.err = err
+	}
+}
+
 func (a *Assembler) ClearScreen() {
 	a.buildArray(0x00, 0xE0)
 }
 
+func (a *Assembler) ScrollDown(n byte) {
+	a.buildArray(0x00, 0xC0|n)
+}
+
+func (a *Assembler) ScrollRight() {
+	a.buildArray(0x00, 0xFB)
+}
+
+func (a *Assembler) ScrollLeft() {
+	a.buildArray(0x00, 0xFC)
+}
+
+func (a *Assembler) Exit() {
+	a.buildArray(0x00, 0xFD)
+}
+
+func (a *Assembler) LowResolution() {
+	a.buildArray(0x00, 0xFE)
+}
+
+func (a *Assembler) HighResolution() {
+	a.buildArray(0x00, 0xFF)
+}
+
 func (a *Assembler) Jump(address uint16) {
+	a.checkAddress(address)
 	instruction := 0x10 | extractFirstByte(address)
 	a.buildArray(instruction, extractSecondByte(address))
 }
 
 func (a *Assembler) Sub(address uint16) {
+	a.checkAddress(address)
 	instruction := 0x20 | extractFirstByte(address)
 	a.buildArray(instruction, extractSecondByte(address))
 }
@@ -89,11 +178,13 @@ func (a *Assembler) ShiftLeft(xRegister byte, yRegister byte) {
 }
 
 func (a *Assembler) SetIndexRegister(value uint16) {
+	a.checkAddress(value)
 	instruction := 0xA0 | extractFirstByte(value)
 	a.buildArray(instruction, extractSecondByte(value))
 }
 
 func (a *Assembler) SetJumpWithOffset(address uint16) {
+	a.checkAddress(address)
 	instruction := 0xB0 | extractFirstByte(address)
 	a.buildArray(instruction, extractSecondByte(address))
 }
@@ -138,10 +229,39 @@ func (a *Assembler) FontChar(xRegister byte) {
 	a.buildArray(0xF0+xRegister, 0x29)
 }
 
+func (a *Assembler) LargeFontChar(xRegister byte) {
+	a.buildArray(0xF0+xRegister, 0x30)
+}
+
 func (a *Assembler) BCD(xRegister byte) {
 	a.buildArray(0xF0+xRegister, 0x33)
 }
 
+func (a *Assembler) LongSetIndexRegister(address uint16) {
+	a.buildArray(0xF0, 0x00)
+	a.buildArray(extractFirstByte(address), extractSecondByte(address))
+}
+
+func (a *Assembler) SelectPlanes(planes byte) {
+	a.buildArray(0xF0+planes, 0x01)
+}
+
+func (a *Assembler) LoadAudioPattern() {
+	a.buildArray(0xF0, 0x02)
+}
+
+func (a *Assembler) SetPitch(xRegister byte) {
+	a.buildArray(0xF0+xRegister, 0x3A)
+}
+
+func (a *Assembler) SaveRange(xRegister byte, yRegister byte) {
+	a.buildArray(0x50+xRegister, (yRegister<<4)|2)
+}
+
+func (a *Assembler) LoadRange(xRegister byte, yRegister byte) {
+	a.buildArray(0x50+xRegister, (yRegister<<4)|3)
+}
+
 func (a *Assembler) Store(xRegister byte) {
 	a.buildArray(0xF0+xRegister, 0x55)
 }
@@ -150,6 +270,14 @@ func (a *Assembler) Load(xRegister byte) {
 	a.buildArray(0xF0+xRegister, 0x65)
 }
 
+func (a *Assembler) StoreFlags(xRegister byte) {
+	a.buildArray(0xF0+xRegister, 0x75)
+}
+
+func (a *Assembler) LoadFlags(xRegister byte) {
+	a.buildArray(0xF0+xRegister, 0x85)
+}
+
 func (a *Assembler) Data(bytes []byte) {
 	a.code = append(a.code, bytes...)
 }
@@ -159,6 +287,22 @@ func (a *Assembler) buildArray(key byte, value byte) {
 	a.code = append(a.code, opcodes...)
 }
 
-func (a *Assembler) Assemble() []byte {
-	return a.code
+// Assemble fills in the addresses of labels and returns the program, or the first error found: a
+// label that is undefined or defined twice, or an address that does not fit in 12 bits.
+func (a *Assembler) Assemble() ([]byte, error) {
+	if a.err != nil {
+		return nil, a.err
+	}
+	for _, fixup := range a.fixups {
+		address, ok := a.labels[fixup.label]
+		if !ok {
+			return nil, fmt.Errorf("label %s is not defined", fixup.label)
+		}
+		if address > maxAddress {
+			return nil, fmt.Errorf("label %s is at 0x%X, which does not fit in 12 bits", fixup.label, address)
+		}
+		a.code[fixup.offset] = a.code[fixup.offset]&0xF0 | extractFirstByte(address)
+		a.code[fixup.offset+1] = extractSecondByte(address)
+	}
+	return a.code, nil
 }
@@ -9,227 +9,268 @@ type AssemblerTestSuite struct {
 	suite.Suite
 }
 
+func (suite *AssemblerTestSuite) assemble(theAssembler *Assembler) []byte {
+	code, err := theAssembler.Assemble()
+	suite.Require().NoError(err)
+	return code
+}
+
 func (suite *AssemblerTestSuite) TestClearScreen() {
 	theAssembler := NewAssembler()
 	theAssembler.ClearScreen()
-	suite.Equal([]byte{0x00, 0xE0}, theAssembler.Assemble())
+	suite.Equal([]byte{0x00, 0xE0}, suite.assemble(theAssembler))
+}
+
+func (suite *AssemblerTestSuite) TestSuperChipScreenInstructions() {
+	theAssembler := NewAssembler()
+	theAssembler.ScrollDown(5)
+	theAssembler.ScrollRight()
+	theAssembler.ScrollLeft()
+	theAssembler.Exit()
+	theAssembler.LowResolution()
+	theAssembler.HighResolution()
+	suite.Equal([]byte{0x00, 0xC5, 0x00, 0xFB, 0x00, 0xFC, 0x00, 0xFD, 0x00, 0xFE, 0x00, 0xFF}, suite.assemble(theAssembler))
+}
+
+func (suite *AssemblerTestSuite) TestSuperChipRegisterInstructions() {
+	theAssembler := NewAssembler()
+	theAssembler.LargeFontChar(1)
+	theAssembler.StoreFlags(7)
+	theAssembler.LoadFlags(3)
+	suite.Equal([]byte{0xF1, 0x30, 0xF7, 0x75, 0xF3, 0x85}, suite.assemble(theAssembler))
+}
+
+func (suite *AssemblerTestSuite) TestXOChipInstructions() {
+	theAssembler := NewAssembler()
+	theAssembler.LongSetIndexRegister(0xABCD)
+	theAssembler.SelectPlanes(3)
+	theAssembler.SaveRange(2, 5)
+	theAssembler.LoadRange(5, 2)
+	suite.Equal([]byte{0xF0, 0x00, 0xAB, 0xCD, 0xF3, 0x01, 0x52, 0x52, 0x55, 0x23}, suite.assemble(theAssembler))
+}
+
+func (suite *AssemblerTestSuite) TestXOChipAudioInstructions() {
+	theAssembler := NewAssembler()
+	theAssembler.LoadAudioPattern()
+	theAssembler.SetPitch(4)
+	suite.Equal([]byte{0xF0, 0x02, 0xF4, 0x3A}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestJump() {
 	theAssembler := NewAssembler()
 	theAssembler.Jump(0x300)
-	suite.Equal([]byte{0x13, 0x00}, theAssembler.Assemble())
+	suite.Equal([]byte{0x13, 0x00}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestSubroutine() {
 	theAssembler := NewAssembler()
 	theAssembler.Sub(0x643)
-	suite.Equal([]byte{0x26, 0x43}, theAssembler.Assemble())
+	suite.Equal([]byte{0x26, 0x43}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestReturn() {
 	theAssembler := NewAssembler()
 	theAssembler.Return()
-	suite.Equal([]byte{0x00, 0xEE}, theAssembler.Assemble())
+	suite.Equal([]byte{0x00, 0xEE}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestSkipIfEqual() {
 	theAssembler := NewAssembler()
 	theAssembler.SkipIfEqual(1, 0xDD)
-	suite.Equal([]byte{0x31, 0xDD}, theAssembler.Assemble())
+	suite.Equal([]byte{0x31, 0xDD}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestSkipIfNotEqual() {
 	theAssembler := NewAssembler()
 	theAssembler.SkipIfNotEqual(2, 0xCD)
-	suite.Equal([]byte{0x42, 0xCD}, theAssembler.Assemble())
+	suite.Equal([]byte{0x42, 0xCD}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestSkipIfRegistersEqual() {
 	theAssembler := NewAssembler()
 	theAssembler.SkipIfRegistersEqual(3, 4)
-	suite.Equal([]byte{0x53, 0x40}, theAssembler.Assemble())
+	suite.Equal([]byte{0x53, 0x40}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestSkipIfRegistersNotEqual() {
 	theAssembler := NewAssembler()
 	theAssembler.SkipIfRegistersNotEqual(2, 7)
-	suite.Equal([]byte{0x92, 0x70}, theAssembler.Assemble())
+	suite.Equal([]byte{0x92, 0x70}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestSetRegister0() {
 	theAssembler := NewAssembler()
 	theAssembler.SetRegister(0, 0x00)
-	suite.Equal([]byte{0x60, 0x00}, theAssembler.Assemble())
+	suite.Equal([]byte{0x60, 0x00}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestSetRegister1() {
 	theAssembler := NewAssembler()
 	theAssembler.SetRegister(1, 0x10)
-	suite.Equal([]byte{0x61, 0x10}, theAssembler.Assemble())
+	suite.Equal([]byte{0x61, 0x10}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestSetMultipleRegisters() {
 	theAssembler := NewAssembler()
 	theAssembler.SetRegister(1, 0x10)
 	theAssembler.SetRegister(2, 0x22)
-	suite.Equal([]byte{0x61, 0x10, 0x62, 0x22}, theAssembler.Assemble())
+	suite.Equal([]byte{0x61, 0x10, 0x62, 0x22}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestAddToRegister() {
 	theAssembler := NewAssembler()
 	theAssembler.AddToRegister(0, 0x33)
-	suite.Equal([]byte{0x70, 0x33}, theAssembler.Assemble())
+	suite.Equal([]byte{0x70, 0x33}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestSet() {
 	theAssembler := NewAssembler()
 	theAssembler.Set(6, 1)
-	suite.Equal([]byte{0x86, 0x10}, theAssembler.Assemble())
+	suite.Equal([]byte{0x86, 0x10}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestOr() {
 	theAssembler := NewAssembler()
 	theAssembler.Or(2, 3)
-	suite.Equal([]byte{0x82, 0x31}, theAssembler.Assemble())
+	suite.Equal([]byte{0x82, 0x31}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestAnd() {
 	theAssembler := NewAssembler()
 	theAssembler.And(4, 5)
-	suite.Equal([]byte{0x84, 0x52}, theAssembler.Assemble())
+	suite.Equal([]byte{0x84, 0x52}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestXor() {
 	theAssembler := NewAssembler()
 	theAssembler.Xor(2, 6)
-	suite.Equal([]byte{0x82, 0x63}, theAssembler.Assemble())
+	suite.Equal([]byte{0x82, 0x63}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestAdd() {
 	theAssembler := NewAssembler()
 	theAssembler.Add(1, 5)
-	suite.Equal([]byte{0x81, 0x54}, theAssembler.Assemble())
+	suite.Equal([]byte{0x81, 0x54}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestSubtract() {
 	theAssembler := NewAssembler()
 	theAssembler.Subtract(6, 5)
-	suite.Equal([]byte{0x86, 0x55}, theAssembler.Assemble())
+	suite.Equal([]byte{0x86, 0x55}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestSubtractLast() {
 	theAssembler := NewAssembler()
 	theAssembler.SubtractLast(1, 2)
-	suite.Equal([]byte{0x81, 0x27}, theAssembler.Assemble())
+	suite.Equal([]byte{0x81, 0x27}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestShiftRight() {
 	theAssembler := NewAssembler()
 	theAssembler.ShiftRight(0, 2)
-	suite.Equal([]byte{0x80, 0x26}, theAssembler.Assemble())
+	suite.Equal([]byte{0x80, 0x26}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestShiftLeft() {
 	theAssembler := NewAssembler()
 	theAssembler.ShiftLeft(1, 2)
-	suite.Equal([]byte{0x81, 0x2E}, theAssembler.Assemble())
+	suite.Equal([]byte{0x81, 0x2E}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestIndexRegister() {
 	theAssembler := NewAssembler()
 	theAssembler.SetIndexRegister(0x123)
-	suite.Equal([]byte{0xA1, 0x23}, theAssembler.Assemble())
+	suite.Equal([]byte{0xA1, 0x23}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestJumpWithOffset() {
 	theAssembler := NewAssembler()
 	theAssembler.SetJumpWithOffset(0x39A)
-	suite.Equal([]byte{0xB3, 0x9A}, theAssembler.Assemble())
+	suite.Equal([]byte{0xB3, 0x9A}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestRandom() {
 	theAssembler := NewAssembler()
 	theAssembler.Random(2, 0x66)
-	suite.Equal([]byte{0xC2, 0x66}, theAssembler.Assemble())
+	suite.Equal([]byte{0xC2, 0x66}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestDisplay() {
 	theAssembler := NewAssembler()
 	theAssembler.Display(6, 4, 5)
-	suite.Equal([]byte{0xD6, 0x45}, theAssembler.Assemble())
+	suite.Equal([]byte{0xD6, 0x45}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestSkipIfKeyPressed() {
 	theAssembler := NewAssembler()
 	theAssembler.SkipIfKeyPressed(2)
-	suite.Equal([]byte{0xE2, 0x9E}, theAssembler.Assemble())
+	suite.Equal([]byte{0xE2, 0x9E}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestSkipIfKeyNotPressed() {
 	theAssembler := NewAssembler()
 	theAssembler.SkipIfKeyNotPressed(4)
-	suite.Equal([]byte{0xE4, 0xA1}, theAssembler.Assemble())
+	suite.Equal([]byte{0xE4, 0xA1}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestGetDelayTimer() {
 	theAssembler := NewAssembler()
 	theAssembler.GetDelayTimer(4)
-	suite.Equal([]byte{0xF4, 0x07}, theAssembler.Assemble())
+	suite.Equal([]byte{0xF4, 0x07}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestSetDelayTimer() {
 	theAssembler := NewAssembler()
 	theAssembler.SetDelayTimer(1)
-	suite.Equal([]byte{0xF1, 0x15}, theAssembler.Assemble())
+	suite.Equal([]byte{0xF1, 0x15}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestSetSoundTimer() {
 	theAssembler := NewAssembler()
 	theAssembler.SetSoundTimer(3)
-	suite.Equal([]byte{0xF3, 0x18}, theAssembler.Assemble())
+	suite.Equal([]byte{0xF3, 0x18}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestAddToIndex() {
 	theAssembler := NewAssembler()
 	theAssembler.AddToIndex(5)
-	suite.Equal([]byte{0xF5, 0x1E}, theAssembler.Assemble())
+	suite.Equal([]byte{0xF5, 0x1E}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestGetKey() {
 	theAssembler := NewAssembler()
 	theAssembler.GetKey(5)
-	suite.Equal([]byte{0xF5, 0x0A}, theAssembler.Assemble())
+	suite.Equal([]byte{0xF5, 0x0A}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestFontChar() {
 	theAssembler := NewAssembler()
 	theAssembler.FontChar(7)
-	suite.Equal([]byte{0xF7, 0x29}, theAssembler.Assemble())
+	suite.Equal([]byte{0xF7, 0x29}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestBCD() {
 	theAssembler := NewAssembler()
 	theAssembler.BCD(6)
-	suite.Equal([]byte{0xF6, 0x33}, theAssembler.Assemble())
+	suite.Equal([]byte{0xF6, 0x33}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestStore() {
 	theAssembler := NewAssembler()
 	theAssembler.Store(4)
-	suite.Equal([]byte{0xF4, 0x55}, theAssembler.Assemble())
+	suite.Equal([]byte{0xF4, 0x55}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestLoad() {
 	theAssembler := NewAssembler()
 	theAssembler.Load(3)
-	suite.Equal([]byte{0xF3, 0x65}, theAssembler.Assemble())
+	suite.Equal([]byte{0xF3, 0x65}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestData() {
 	theAssembler := NewAssembler()
 	theAssembler.Data([]byte{0xF3, 0x65, 0xc1, 0xdd, 0xef})
-	suite.Equal([]byte{0xF3, 0x65, 0xc1, 0xdd, 0xef}, theAssembler.Assemble())
+	suite.Equal([]byte{0xF3, 0x65, 0xc1, 0xdd, 0xef}, suite.assemble(theAssembler))
 }
 
 func (suite *AssemblerTestSuite) TestProgram() {
@@ -299,7 +340,60 @@ func (suite *AssemblerTestSuite) TestProgram() {
 
 		0xF3, 0x0A,
 	}
-	suite.Equal(andysProgram, theAssembler.Assemble())
+	suite.Equal(andysProgram, suite.assemble(theAssembler))
+}
+
+func (suite *AssemblerTestSuite) TestLabelsResolveForwardAndBackward() {
+	theAssembler := NewAssembler()
+	theAssembler.JumpTo("main")
+	theAssembler.Label("sprite")
+	theAssembler.Data([]byte{0xF0, 0x90})
+	theAssembler.Label("main")
+	theAssembler.SetIndexToLabel("sprite")
+	theAssembler.CallLabel("draw")
+	theAssembler.JumpTo("main")
+	theAssembler.Label("draw")
+	theAssembler.JumpWithOffsetTo("sprite")
+	suite.Equal([]byte{
+		0x12, 0x04,
+		0xF0, 0x90,
+		0xA2, 0x02,
+		0x22, 0x0A,
+		0x12, 0x04,
+		0xB2, 0x02,
+	}, suite.assemble(theAssembler))
+}
+
+func (suite *AssemblerTestSuite) TestUndefinedLabel() {
+	theAssembler := NewAssembler()
+	theAssembler.JumpTo("nowhere")
+	_, err := theAssembler.Assemble()
+	suite.EqualError(err, "label nowhere is not defined")
+}
+
+func (suite *AssemblerTestSuite) TestDuplicateLabel() {
+	theAssembler := NewAssembler()
+	theAssembler.Label("loop")
+	theAssembler.ClearScreen()
+	theAssembler.Label("loop")
+	_, err := theAssembler.Assemble()
+	suite.EqualError(err, "label loop is defined more than once")
+}
+
+func (suite *AssemblerTestSuite) TestLabelBeyond12Bits() {
+	theAssembler := NewAssembler()
+	theAssembler.JumpTo("far")
+	theAssembler.Data(make([]byte, 0x1000))
+	theAssembler.Label("far")
+	_, err := theAssembler.Assemble()
+	suite.EqualError(err, "label far is at 0x1202, which does not fit in 12 bits")
+}
+
+func (suite *AssemblerTestSuite) TestAddressBeyond12Bits() {
+	theAssembler := NewAssembler()
+	theAssembler.SetIndexRegister(0x1000)
+	_, err := theAssembler.Assemble()
+	suite.EqualError(err, "address 0x1000 does not fit in 12 bits")
 }
 
 func TestAssemblerTestSuite(t *testing.T) {
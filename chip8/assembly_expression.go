@@ -0,0 +1,275 @@
+package chip8
+
+import "fmt"
+
+type sourcePosition struct {
+	line   int
+	column int
+}
+
+func (p sourcePosition) errorf(format string, args ...interface{}) *AssemblyError {
+	return &AssemblyError{Line: p.line, Column: p.column, Message: fmt.Sprintf(format, args...)}
+}
+
+// expression is an arithmetic expression, evaluated once every label has an address.
+type expression interface {
+	evaluate(symbols symbolTable) (int, *AssemblyError)
+	position() sourcePosition
+}
+
+type symbolTable interface {
+	lookup(name string, at sourcePosition) (int, *AssemblyError)
+}
+
+type numberExpression struct {
+	at    sourcePosition
+	value int
+}
+
+type symbolExpression struct {
+	at   sourcePosition
+	name string
+}
+
+type unaryExpression struct {
+	at       sourcePosition
+	operator string
+	operand  expression
+}
+
+type binaryExpression struct {
+	at       sourcePosition
+	operator string
+	left     expression
+	right    expression
+}
+
+func (e numberExpression) evaluate(symbols symbolTable) (int, *AssemblyError) {
+	return e.value, nil
+}
+
+func (e numberExpression) position() sourcePosition {
+	return e.at
+}
+
+func (e symbolExpression) evaluate(symbols symbolTable) (int, *AssemblyError) {
+	return symbols.lookup(e.name, e.at)
+}
+
+func (e symbolExpression) position() sourcePosition {
+	return e.at
+}
+
+func (e unaryExpression) evaluate(symbols symbolTable) (int, *AssemblyError) {
+	value, err := e.operand.evaluate(symbols)
+	if err != nil {
+		return 0, err
+	}
+	switch e.operator {
+	case "-":
+		return -value, nil
+	case "~":
+		return ^value, nil
+	case "!":
+		return truth(value == 0), nil
+	}
+	return value, nil
+}
+
+func (e unaryExpression) position() sourcePosition {
+	return e.at
+}
+
+func (e binaryExpression) evaluate(symbols symbolTable) (int, *AssemblyError) {
+	left, err := e.left.evaluate(symbols)
+	if err != nil {
+		return 0, err
+	}
+	right, err := e.right.evaluate(symbols)
+	if err != nil {
+		return 0, err
+	}
+	switch e.operator {
+	case "+":
+		return left + right, nil
+	case "-":
+		return left - right, nil
+	case "*":
+		return left * right, nil
+	case "/", "%":
+		if right == 0 {
+			return 0, e.right.position().errorf("division by zero")
+		}
+		if e.operator == "/" {
+			return left / right, nil
+		}
+		return left % right, nil
+	case "&":
+		return left & right, nil
+	case "|":
+		return left | right, nil
+	case "^":
+		return left ^ right, nil
+	case "<<", ">>":
+		if right < 0 || right > 31 {
+			return 0, e.right.position().errorf("shift count %d out of range", right)
+		}
+		if e.operator == "<<" {
+			return left << right, nil
+		}
+		return left >> right, nil
+	case "==":
+		return truth(left == right), nil
+	case "!=":
+		return truth(left != right), nil
+	case "<":
+		return truth(left < right), nil
+	case "<=":
+		return truth(left <= right), nil
+	case ">":
+		return truth(left > right), nil
+	case ">=":
+		return truth(left >= right), nil
+	case "&&":
+		return truth(left != 0 && right != 0), nil
+	case "||":
+		return truth(left != 0 || right != 0), nil
+	}
+	return 0, e.at.errorf("unknown operator %s", e.operator)
+}
+
+// truth is 1 for true and 0 for false, as in C.
+func truth(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (e binaryExpression) position() sourcePosition {
+	return e.at
+}
+
+// binaryPrecedence lists the binary operators from the loosest binding to the tightest, as in C.
+var binaryPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"|"},
+	{"^"},
+	{"&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// lineParser reads the tokens of a single line of source.
+type lineParser struct {
+	tokens []token
+	index  int
+	line   int
+	end    int
+	// registers allows V0 to VF as symbols, for breakpoint conditions.
+	registers bool
+}
+
+func (p *lineParser) atEnd() bool {
+	return p.index >= len(p.tokens)
+}
+
+func (p *lineParser) peek() token {
+	if p.atEnd() {
+		return token{column: p.end}
+	}
+	return p.tokens[p.index]
+}
+
+func (p *lineParser) next() token {
+	t := p.peek()
+	p.index++
+	return t
+}
+
+func (p *lineParser) positionOf(t token) sourcePosition {
+	return sourcePosition{line: p.line, column: t.column}
+}
+
+func (p *lineParser) parseExpression() (expression, *AssemblyError) {
+	return p.parseBinary(0)
+}
+
+func (p *lineParser) parseBinary(level int) (expression, *AssemblyError) {
+	if level == len(binaryPrecedence) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		operator := ""
+		for _, candidate := range binaryPrecedence[level] {
+			if t.is(candidate) {
+				operator = candidate
+			}
+		}
+		if operator == "" {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpression{at: p.positionOf(t), operator: operator, left: left, right: right}
+	}
+}
+
+func (p *lineParser) parseUnary() (expression, *AssemblyError) {
+	t := p.peek()
+	if t.is("-") || t.is("~") || t.is("+") || t.is("!") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryExpression{at: p.positionOf(t), operator: t.text, operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *lineParser) parsePrimary() (expression, *AssemblyError) {
+	if p.atEnd() {
+		return nil, p.positionOf(p.peek()).errorf("expected an expression")
+	}
+	t := p.next()
+	switch {
+	case t.kind == numberToken:
+		return numberExpression{at: p.positionOf(t), value: t.value}, nil
+	case t.kind == identifierToken:
+		if _, ok := registerNumber(t.text); ok && !p.registers {
+			return nil, p.positionOf(t).errorf("register %s cannot be used in an expression", t.text)
+		}
+		return symbolExpression{at: p.positionOf(t), name: t.text}, nil
+	case t.is("("):
+		inner, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); !closing.is(")") {
+			return nil, p.positionOf(closing).errorf("expected \")\" but found %s", p.describe(closing))
+		}
+		return inner, nil
+	}
+	return nil, p.positionOf(t).errorf("expected an expression but found %s", p.describe(t))
+}
+
+// describe names a token for an error message, the zero token being the end of the line.
+func (p *lineParser) describe(t token) string {
+	if t.text == "" && t.kind == identifierToken {
+		return "end of line"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
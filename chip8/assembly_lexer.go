@@ -0,0 +1,113 @@
+package chip8
+
+import (
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	identifierToken tokenKind = iota
+	numberToken
+	stringToken
+	punctuationToken
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	value  int
+	column int
+}
+
+// Two character operators are listed first so that "<<" is not read as two "<"
+var punctuation = []string{"<<", ">>", "==", "!=", "<=", ">=", "&&", "||", ",", ":", "(", ")", "[", "]", "+", "-", "*", "/", "%", "&", "|", "^", "~", "!", "<", ">", "="}
+
+// tokenize splits one line of source into tokens, dropping any comment after a semicolon.
+func tokenize(line string, lineNumber int) ([]token, *AssemblyError) {
+	var tokens []token
+	position := 0
+	for position < len(line) {
+		c := line[position]
+		column := position + 1
+		switch {
+		case c == ';':
+			return tokens, nil
+		case c == ' ' || c == '\t' || c == '\r':
+			position++
+		case isIdentifierStart(c):
+			end := position
+			for end < len(line) && isIdentifierPart(line[end]) {
+				end++
+			}
+			tokens = append(tokens, token{kind: identifierToken, text: line[position:end], column: column})
+			position = end
+		case c >= '0' && c <= '9':
+			end := position
+			for end < len(line) && isIdentifierPart(line[end]) {
+				end++
+			}
+			text := line[position:end]
+			value, err := parseNumber(text)
+			if err != nil {
+				return nil, &AssemblyError{Line: lineNumber, Column: column, Message: "invalid number " + text}
+			}
+			tokens = append(tokens, token{kind: numberToken, text: text, value: int(value), column: column})
+			position = end
+		case c == '"':
+			end := position + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, &AssemblyError{Line: lineNumber, Column: column, Message: "unterminated string"}
+			}
+			text, err := strconv.Unquote(line[position : end+1])
+			if err != nil {
+				return nil, &AssemblyError{Line: lineNumber, Column: column, Message: "invalid string " + line[position:end+1]}
+			}
+			tokens = append(tokens, token{kind: stringToken, text: text, column: column})
+			position = end + 1
+		default:
+			matched := false
+			for _, p := range punctuation {
+				if strings.HasPrefix(line[position:], p) {
+					tokens = append(tokens, token{kind: punctuationToken, text: p, column: column})
+					position += len(p)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, &AssemblyError{Line: lineNumber, Column: column, Message: "unexpected character " + strconv.QuoteRune(rune(c))}
+			}
+		}
+	}
+	return tokens, nil
+}
+
+// parseNumber reads decimal, or hex, binary and octal with a 0x, 0b or 0o prefix.
+func parseNumber(text string) (int64, error) {
+	base := 10
+	lower := strings.ToLower(text)
+	if strings.HasPrefix(lower, "0x") || strings.HasPrefix(lower, "0b") || strings.HasPrefix(lower, "0o") {
+		base = 0
+	}
+	return strconv.ParseInt(text, base, 32)
+}
+
+func isIdentifierStart(c byte) bool {
+	return c == '_' || c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentifierPart(c byte) bool {
+	return isIdentifierStart(c) || (c >= '0' && c <= '9')
+}
+
+func (t token) is(text string) bool {
+	return t.kind == punctuationToken && t.text == text
+}
@@ -0,0 +1,22 @@
+package chip8
+
+type AudioInterface interface {
+	// SetTone starts or stops the buzzer as the sound timer becomes non-zero or reaches zero.
+	SetTone(on bool)
+	// SetPattern changes the waveform played by the buzzer, loaded by the XO-CHIP F002 and FX3A.
+	SetPattern(pattern SoundPattern)
+	// EndFrame is called at the end of each 60 Hz frame, so a sound can be rendered a frame at a time.
+	EndFrame()
+}
+
+// NullAudio discards the tone, for running without a sound device.
+type NullAudio struct{}
+
+func (n NullAudio) SetTone(on bool) {
+}
+
+func (n NullAudio) SetPattern(pattern SoundPattern) {
+}
+
+func (n NullAudio) EndFrame() {
+}
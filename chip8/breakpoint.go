@@ -0,0 +1,278 @@
+package chip8
+
+import (
+	"fmt"
+	"strings"
+)
+
+type BreakpointKind int
+
+const (
+	// BreakOnPC stops before the instruction at Address is executed.
+	BreakOnPC BreakpointKind = iota
+	// BreakOnRead stops after an instruction reads the byte at Address, e.g. DXYN or FX65.
+	BreakOnRead
+	// BreakOnWrite stops after an instruction writes the byte at Address, e.g. FX55 or FX33.
+	BreakOnWrite
+	// BreakOnRegister stops after an instruction changes the value of V0 to VF.
+	BreakOnRegister
+	// BreakOnCondition stops after an instruction makes Condition true.
+	BreakOnCondition
+	// BreakOnIndex stops after an instruction changes the value of I.
+	BreakOnIndex
+)
+
+// Breakpoint stops Step and Run when the program reaches an address, touches memory, changes a
+// register or I, or makes a condition true.
+type Breakpoint struct {
+	ID      int
+	Kind    BreakpointKind
+	Address uint16
+	// Register is the X of VX for BreakOnRegister.
+	Register byte
+	// Condition is an expression such as "V3 == 0x10 && I > 0x300". It can use V0 to VF, I, PC, SP,
+	// DT and ST. For kinds other than BreakOnCondition it must also be true for the breakpoint to fire.
+	Condition string
+
+	condition expression
+	// wasTrue makes BreakOnCondition fire once each time its condition becomes true
+	wasTrue bool
+}
+
+func (b Breakpoint) String() string {
+	var description string
+	switch b.Kind {
+	case BreakOnPC:
+		description = fmt.Sprintf("breakpoint %d at %03X", b.ID, b.Address)
+	case BreakOnRead:
+		description = fmt.Sprintf("watchpoint %d on read of %03X", b.ID, b.Address)
+	case BreakOnWrite:
+		description = fmt.Sprintf("watchpoint %d on write to %03X", b.ID, b.Address)
+	case BreakOnRegister:
+		description = fmt.Sprintf("watchpoint %d on V%X", b.ID, b.Register)
+	case BreakOnIndex:
+		description = fmt.Sprintf("watchpoint %d on I", b.ID)
+	case BreakOnCondition:
+		return fmt.Sprintf("breakpoint %d when %s", b.ID, b.Condition)
+	}
+	if b.Condition != "" {
+		description += " if " + b.Condition
+	}
+	return description
+}
+
+// BreakpointHit describes the breakpoint that stopped the VM. Run returns it as an error.
+type BreakpointHit struct {
+	Breakpoint Breakpoint
+	// PC is the address of the instruction that fired the breakpoint. For BreakOnPC it hasn't been
+	// executed yet, for the other kinds it has.
+	PC uint16
+	// OldValue and NewValue are the byte written for BreakOnWrite, the register for BreakOnRegister
+	// or I for BreakOnIndex.
+	OldValue uint16
+	NewValue uint16
+}
+
+func (h *BreakpointHit) Error() string {
+	switch h.Breakpoint.Kind {
+	case BreakOnWrite, BreakOnRegister:
+		return fmt.Sprintf("%s hit at %03X: %02X -> %02X", h.Breakpoint, h.PC, h.OldValue, h.NewValue)
+	case BreakOnIndex:
+		return fmt.Sprintf("%s hit at %03X: %03X -> %03X", h.Breakpoint, h.PC, h.OldValue, h.NewValue)
+	}
+	return fmt.Sprintf("%s hit at %03X", h.Breakpoint, h.PC)
+}
+
+// memoryAccess is a range of memory read or written by the instruction being executed.
+type memoryAccess struct {
+	write   bool
+	address int
+	length  int
+	// before holds the bytes that were about to be written over
+	before []byte
+}
+
+// AddBreakpoint parses any condition and adds the breakpoint, returning its ID.
+func (v *VM) AddBreakpoint(b Breakpoint) (int, error) {
+	switch b.Kind {
+	case BreakOnRead, BreakOnWrite:
+		if int(b.Address) >= len(v.Memory) {
+			return 0, fmt.Errorf("address %X is outside memory", b.Address)
+		}
+	case BreakOnRegister:
+		if b.Register > 0xF {
+			return 0, fmt.Errorf("there is no register V%X", b.Register)
+		}
+	case BreakOnCondition:
+		if b.Condition == "" {
+			return 0, fmt.Errorf("a condition is needed")
+		}
+	}
+	b.condition = nil
+	if b.Condition != "" {
+		condition, err := v.parseCondition(b.Condition)
+		if err != nil {
+			return 0, err
+		}
+		b.condition = condition
+		b.wasTrue = v.conditionTrue(&b)
+	}
+	v.nextBreakpointID++
+	b.ID = v.nextBreakpointID
+	v.breakpoints = append(v.breakpoints, &b)
+	return b.ID, nil
+}
+
+// RemoveBreakpoint removes the breakpoint with the given ID, reporting whether there was one.
+func (v *VM) RemoveBreakpoint(id int) bool {
+	for n, b := range v.breakpoints {
+		if b.ID == id {
+			v.breakpoints = append(v.breakpoints[:n], v.breakpoints[n+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (v *VM) ClearBreakpoints() {
+	v.breakpoints = nil
+}
+
+// Breakpoints returns a copy of the breakpoints in the order they were added.
+func (v *VM) Breakpoints() []Breakpoint {
+	breakpoints := make([]Breakpoint, len(v.breakpoints))
+	for n, b := range v.breakpoints {
+		breakpoints[n] = *b
+	}
+	return breakpoints
+}
+
+// BreakpointHit returns the breakpoint that stopped the last Step, or nil if none did.
+func (v *VM) BreakpointHit() *BreakpointHit {
+	return v.breakpointHit
+}
+
+func (v *VM) parseCondition(text string) (expression, error) {
+	tokens, err := tokenize(text, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %s", text, err.Message)
+	}
+	p := &lineParser{tokens: tokens, end: len(text) + 1, registers: true}
+	condition, err := p.parseExpression()
+	if err == nil && !p.atEnd() {
+		err = p.positionOf(p.peek()).errorf("unexpected %s", p.describe(p.peek()))
+	}
+	if err == nil {
+		_, err = condition.evaluate(vmSymbols{v})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %s at column %d", text, err.Message, err.Column)
+	}
+	return condition, nil
+}
+
+func (v *VM) conditionTrue(b *Breakpoint) bool {
+	if b.condition == nil {
+		return true
+	}
+	value, err := b.condition.evaluate(vmSymbols{v})
+	return err == nil && value != 0
+}
+
+// vmSymbols lets conditions read the registers of the VM.
+type vmSymbols struct {
+	vm *VM
+}
+
+func (s vmSymbols) lookup(name string, at sourcePosition) (int, *AssemblyError) {
+	if register, ok := registerNumber(name); ok {
+		return int(s.vm.registers[register]), nil
+	}
+	switch strings.ToUpper(name) {
+	case "I":
+		return int(s.vm.indexRegister), nil
+	case "PC":
+		return int(s.vm.pc), nil
+	case "SP":
+		return s.vm.theStack.length(), nil
+	case "DT":
+		return int(s.vm.timers.Delay()), nil
+	case "ST":
+		return int(s.vm.timers.Sound()), nil
+	}
+	return 0, at.errorf("unknown name %s", name)
+}
+
+// checkPCBreakpoints is called before each instruction. After stopping at an address, the next
+// Step executes the instruction there rather than stopping again.
+func (v *VM) checkPCBreakpoints() bool {
+	resuming := v.resuming && v.resumeAt == v.pc
+	v.resuming = false
+	if resuming {
+		return false
+	}
+	for _, b := range v.breakpoints {
+		if b.Kind == BreakOnPC && b.Address == v.pc && v.conditionTrue(b) {
+			v.breakpointHit = &BreakpointHit{Breakpoint: *b, PC: v.pc}
+			v.resuming = true
+			v.resumeAt = v.pc
+			return true
+		}
+	}
+	return false
+}
+
+// checkBreakpoints is called after each instruction, with the address of the instruction and the
+// registers and I before it executed.
+func (v *VM) checkBreakpoints(pc uint16, registersBefore [16]byte, indexBefore uint16) bool {
+	var hit *BreakpointHit
+	for _, b := range v.breakpoints {
+		fired := false
+		h := BreakpointHit{Breakpoint: *b, PC: pc}
+		switch b.Kind {
+		case BreakOnRead, BreakOnWrite:
+			for _, access := range v.accesses {
+				offset := int(b.Address) - access.address
+				if access.write == (b.Kind == BreakOnWrite) && offset >= 0 && offset < access.length {
+					fired = true
+					if access.write {
+						h.OldValue = uint16(access.before[offset])
+						h.NewValue = uint16(v.Memory[b.Address])
+					}
+				}
+			}
+		case BreakOnRegister:
+			h.OldValue = uint16(registersBefore[b.Register])
+			h.NewValue = uint16(v.registers[b.Register])
+			fired = h.OldValue != h.NewValue
+		case BreakOnIndex:
+			h.OldValue = indexBefore
+			h.NewValue = v.indexRegister
+			fired = h.OldValue != h.NewValue
+		case BreakOnCondition:
+			isTrue := v.conditionTrue(b)
+			fired = isTrue && !b.wasTrue
+			b.wasTrue = isTrue
+		}
+		if fired && hit == nil && v.conditionTrue(b) {
+			hit = &h
+		}
+	}
+	v.accesses = v.accesses[:0]
+	if hit != nil {
+		v.breakpointHit = hit
+	}
+	return hit != nil
+}
+
+// recordAccess notes the memory an instruction is about to read or write, for watchpoints.
+func (v *VM) recordAccess(write bool, address uint16, length int) {
+	if len(v.breakpoints) == 0 {
+		return
+	}
+	access := memoryAccess{write: write, address: int(address), length: length}
+	if write {
+		access.before = append([]byte(nil), v.Memory[address:int(address)+length]...)
+	}
+	v.accesses = append(v.accesses, access)
+}
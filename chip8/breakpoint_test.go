@@ -0,0 +1,189 @@
+package chip8
+
+import (
+	"errors"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type BreakpointTestSuite struct {
+	suite.Suite
+	vm *VM
+}
+
+func (suite *BreakpointTestSuite) SetupTest() {
+	suite.vm = NewVM(&mockDisplay{eventType: KeyboardEvent}, MockRandom{55}, QuirksChip48)
+	suite.vm.SetClock(NewVirtualClock())
+
+	a := NewAssembler()
+	a.SetRegister(3, 0x10)
+	a.SetRegister(4, 123)
+	a.SetIndexRegister(0x300)
+	a.Label("loop")
+	a.AddToRegister(0, 1)
+	a.Store(0)
+	a.BCD(4)
+	a.Display(0, 0, 1)
+	a.JumpTo("loop")
+	code, err := a.Assemble()
+	suite.Require().NoError(err)
+	suite.vm.Load(code)
+}
+
+func (suite *BreakpointTestSuite) add(b Breakpoint) int {
+	id, err := suite.vm.AddBreakpoint(b)
+	suite.Require().NoError(err)
+	return id
+}
+
+func (suite *BreakpointTestSuite) stepUntilBreakpoint() *BreakpointHit {
+	for n := 0; n < 100; n++ {
+		result, err := suite.vm.Step()
+		suite.Require().NoError(err)
+		if result == StepBreakpoint {
+			return suite.vm.BreakpointHit()
+		}
+	}
+	suite.FailNow("no breakpoint was hit")
+	return nil
+}
+
+func (suite *BreakpointTestSuite) TestPCBreakpointStopsBeforeTheInstruction() {
+	id := suite.add(Breakpoint{Kind: BreakOnPC, Address: 0x206})
+
+	hit := suite.stepUntilBreakpoint()
+
+	suite.Equal(id, hit.Breakpoint.ID)
+	suite.Equal(uint16(0x206), hit.PC)
+	suite.Equal(uint16(0x206), suite.vm.PC())
+	suite.Equal(byte(0), suite.vm.State().Registers[0])
+	suite.EqualError(hit, "breakpoint 1 at 206 hit at 206")
+}
+
+func (suite *BreakpointTestSuite) TestNextStepLeavesThePCBreakpoint() {
+	suite.add(Breakpoint{Kind: BreakOnPC, Address: 0x206})
+	suite.stepUntilBreakpoint()
+
+	result, err := suite.vm.Step()
+
+	suite.NoError(err)
+	suite.Equal(StepExecuted, result)
+	suite.Nil(suite.vm.BreakpointHit())
+	suite.Equal(byte(1), suite.vm.State().Registers[0])
+	suite.Equal(uint16(0x206), suite.stepUntilBreakpoint().PC, "stops again on the next time round the loop")
+	suite.Equal(uint16(0x206), suite.vm.PC())
+}
+
+func (suite *BreakpointTestSuite) TestWriteWatchpointOnStore() {
+	suite.add(Breakpoint{Kind: BreakOnWrite, Address: 0x300})
+
+	hit := suite.stepUntilBreakpoint()
+
+	suite.Equal(uint16(0x208), hit.PC)
+	suite.Equal(uint16(0), hit.OldValue)
+	suite.Equal(uint16(0x01), hit.NewValue)
+}
+
+func (suite *BreakpointTestSuite) TestWriteWatchpointOnBCD() {
+	suite.add(Breakpoint{Kind: BreakOnWrite, Address: 0x302})
+
+	hit := suite.stepUntilBreakpoint()
+
+	suite.Equal(uint16(0x20A), hit.PC)
+	suite.Equal(uint16(0), hit.OldValue)
+	suite.Equal(uint16(3), hit.NewValue)
+	suite.Equal(uint16(0x20C), suite.vm.PC())
+}
+
+func (suite *BreakpointTestSuite) TestReadWatchpointOnDraw() {
+	suite.add(Breakpoint{Kind: BreakOnRead, Address: 0x300})
+	suite.add(Breakpoint{Kind: BreakOnRead, Address: 0x301})
+
+	hit := suite.stepUntilBreakpoint()
+
+	suite.Equal(1, hit.Breakpoint.ID)
+	suite.Equal(uint16(0x20C), hit.PC)
+}
+
+func (suite *BreakpointTestSuite) TestRegisterWatchpoint() {
+	suite.add(Breakpoint{Kind: BreakOnRegister, Register: 4})
+
+	hit := suite.stepUntilBreakpoint()
+
+	suite.Equal(uint16(0x202), hit.PC)
+	suite.Equal(uint16(0), hit.OldValue)
+	suite.Equal(uint16(123), hit.NewValue)
+	suite.EqualError(hit, "watchpoint 1 on V4 hit at 202: 00 -> 7B")
+}
+
+func (suite *BreakpointTestSuite) TestIndexWatchpoint() {
+	suite.add(Breakpoint{Kind: BreakOnIndex})
+
+	hit := suite.stepUntilBreakpoint()
+
+	suite.Equal(uint16(0x300), hit.NewValue)
+	suite.EqualError(hit, "watchpoint 1 on I hit at 204: 000 -> 300")
+}
+
+func (suite *BreakpointTestSuite) TestConditionFiresWhenItBecomesTrue() {
+	suite.add(Breakpoint{Kind: BreakOnCondition, Condition: "V3 == 0x10 && I > 0x2FF && V0 >= 2"})
+
+	hit := suite.stepUntilBreakpoint()
+
+	suite.Equal(uint16(0x206), hit.PC)
+	suite.Equal(byte(2), suite.vm.State().Registers[0])
+	for n := 0; n < 20; n++ {
+		result, _ := suite.vm.Step()
+		suite.NotEqual(StepBreakpoint, result, "fires again before the condition is false")
+	}
+}
+
+func (suite *BreakpointTestSuite) TestConditionOnPCBreakpoint() {
+	suite.add(Breakpoint{Kind: BreakOnPC, Address: 0x206, Condition: "V0 == 3"})
+
+	hit := suite.stepUntilBreakpoint()
+
+	suite.Equal(uint16(0x206), hit.PC)
+	suite.Equal(byte(3), suite.vm.State().Registers[0])
+}
+
+func (suite *BreakpointTestSuite) TestInvalidBreakpoints() {
+	_, err := suite.vm.AddBreakpoint(Breakpoint{Kind: BreakOnCondition, Condition: "V3 == == 2"})
+	suite.EqualError(err, `invalid condition "V3 == == 2": expected an expression but found "==" at column 7`)
+
+	_, err = suite.vm.AddBreakpoint(Breakpoint{Kind: BreakOnCondition, Condition: "VX > 2"})
+	suite.EqualError(err, `invalid condition "VX > 2": unknown name VX at column 1`)
+
+	_, err = suite.vm.AddBreakpoint(Breakpoint{Kind: BreakOnRegister, Register: 16})
+	suite.Error(err)
+
+	_, err = suite.vm.AddBreakpoint(Breakpoint{Kind: BreakOnWrite, Address: 0x1000})
+	suite.Error(err)
+
+	suite.Empty(suite.vm.Breakpoints())
+}
+
+func (suite *BreakpointTestSuite) TestRemoveBreakpoint() {
+	first := suite.add(Breakpoint{Kind: BreakOnPC, Address: 0x206})
+	second := suite.add(Breakpoint{Kind: BreakOnRegister, Register: 0})
+
+	suite.True(suite.vm.RemoveBreakpoint(first))
+	suite.False(suite.vm.RemoveBreakpoint(first))
+
+	suite.Len(suite.vm.Breakpoints(), 1)
+	suite.Equal(second, suite.stepUntilBreakpoint().Breakpoint.ID)
+}
+
+func (suite *BreakpointTestSuite) TestRunReturnsTheHit() {
+	suite.add(Breakpoint{Kind: BreakOnWrite, Address: 0x300, Condition: "V0 == 5"})
+
+	err := suite.vm.Run()
+
+	var hit *BreakpointHit
+	suite.True(errors.As(err, &hit))
+	suite.Equal(uint16(5), hit.NewValue)
+}
+
+func TestBreakpointSuite(t *testing.T) {
+	suite.Run(t, new(BreakpointTestSuite))
+}
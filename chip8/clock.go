@@ -0,0 +1,44 @@
+package chip8
+
+import "time"
+
+// Clock paces the frames executed by VM.Run.
+type Clock interface {
+	WaitForFrame()
+}
+
+// RealTimeClock paces frames at 60 Hz against the wall clock.
+type RealTimeClock struct {
+	next time.Time
+}
+
+func NewRealTimeClock() *RealTimeClock {
+	return new(RealTimeClock)
+}
+
+func (c *RealTimeClock) WaitForFrame() {
+	now := time.Now()
+	// If we have fallen a long way behind, e.g. the process was suspended, don't try to catch up
+	if c.next.IsZero() || now.Sub(c.next) > frameDuration*4 {
+		c.next = now
+	}
+	c.next = c.next.Add(frameDuration)
+	time.Sleep(time.Until(c.next))
+}
+
+// VirtualClock never waits, so execution is deterministic and as fast as the host allows.
+type VirtualClock struct {
+	frames uint64
+}
+
+func NewVirtualClock() *VirtualClock {
+	return new(VirtualClock)
+}
+
+func (c *VirtualClock) WaitForFrame() {
+	c.frames++
+}
+
+func (c *VirtualClock) Frames() uint64 {
+	return c.frames
+}
@@ -0,0 +1,407 @@
+package chip8
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+)
+
+const debuggerHelp = `step [n]            execute n instructions, default 1
+continue            run until a breakpoint fires, the program halts or Ctrl-C
+break [addr] [if c] stop when PC reaches addr, or list the breakpoints
+break if <c>        stop when a condition such as V3 == 0x10 && I > 0x300 becomes true
+delete <id>         remove a breakpoint or watchpoint
+watch <addr|Vx|I>   stop when a memory byte is written or a register changes
+rwatch <addr>       stop when a memory byte is read
+regs                show the registers
+mem <addr> [len]    dump memory
+disasm [addr] [n]   disassemble n instructions from addr, default the PC
+stack               show the return addresses
+set <Vx|I|PC> <v>   change a register
+trace on|flow|off   print every instruction, or only jumps, calls, returns and skips
+quit                leave the debugger
+`
+
+// Debugger is a command line debugger for a VM, reading commands from in and writing to out.
+type Debugger struct {
+	vm          *VM
+	in          *bufio.Scanner
+	out         io.Writer
+	lastCommand string
+	interrupted int32
+}
+
+func NewDebugger(vm *VM, in io.Reader, out io.Writer) *Debugger {
+	return &Debugger{
+		vm:  vm,
+		in:  bufio.NewScanner(in),
+		out: out,
+	}
+}
+
+// Run reads and executes commands until quit or the end of the input. An empty line repeats the
+// previous command.
+func (d *Debugger) Run() error {
+	d.showNext()
+	for {
+		fmt.Fprint(d.out, "(chip8) ")
+		if !d.in.Scan() {
+			fmt.Fprintln(d.out)
+			return d.in.Err()
+		}
+		line := strings.TrimSpace(d.in.Text())
+		if line == "" {
+			line = d.lastCommand
+		}
+		d.lastCommand = line
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		if args[0] == "quit" || args[0] == "q" {
+			return nil
+		}
+		if err := d.execute(args[0], args[1:]); err != nil {
+			fmt.Fprintln(d.out, err)
+		}
+	}
+}
+
+// Interrupt stops continue at the end of the current frame and goes back to the prompt. It is safe to
+// call from another goroutine, such as a signal handler.
+func (d *Debugger) Interrupt() {
+	atomic.StoreInt32(&d.interrupted, 1)
+}
+
+func (d *Debugger) execute(command string, args []string) error {
+	switch command {
+	case "step", "s":
+		count := 1
+		if len(args) > 0 {
+			n, err := parseNumber(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid count %s", args[0])
+			}
+			count = int(n)
+		}
+		return d.step(count)
+	case "continue", "c":
+		return d.continueRunning()
+	case "break", "b":
+		if len(args) == 0 {
+			for _, b := range d.vm.Breakpoints() {
+				fmt.Fprintln(d.out, b)
+			}
+			return nil
+		}
+		return d.addBreakpoint(args)
+	case "delete":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: delete <id>")
+		}
+		id, err := parseNumber(args[0])
+		if err != nil || !d.vm.RemoveBreakpoint(int(id)) {
+			return fmt.Errorf("no breakpoint %s", args[0])
+		}
+	case "watch", "w", "rwatch":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: %s <addr>", command)
+		}
+		return d.addWatch(command == "rwatch", args[0])
+	case "regs", "r":
+		d.showRegisters()
+	case "mem", "m":
+		return d.showMemory(args)
+	case "disasm", "d":
+		return d.showDisassembly(args)
+	case "stack":
+		stack := d.vm.State().Stack
+		if len(stack) == 0 {
+			fmt.Fprintln(d.out, "stack is empty")
+		}
+		for n := len(stack) - 1; n >= 0; n-- {
+			fmt.Fprintf(d.out, "%2d  %03X\n", n, stack[n])
+		}
+	case "set":
+		return d.set(args)
+	case "trace":
+		switch strings.Join(args, " ") {
+		case "on":
+			d.vm.SetTracer(NewTextTracer(d.out))
+		case "flow":
+			d.vm.SetTracer(NewLevelTracer(NewTextTracer(d.out), TraceFlow))
+		case "off":
+			d.vm.SetTracer(NullTracer{})
+		default:
+			return fmt.Errorf("usage: trace on|flow|off")
+		}
+	case "help", "h", "?":
+		fmt.Fprint(d.out, debuggerHelp)
+	default:
+		return fmt.Errorf("unknown command %s, try help", command)
+	}
+	return nil
+}
+
+func (d *Debugger) step(count int) error {
+	for n := 0; n < count; {
+		result, stop, err := d.stepOnce(n == 0)
+		if stop || err != nil {
+			return err
+		}
+		if result != StepWaiting {
+			n++
+			continue
+		}
+		if state := d.vm.State(); state.WaitingForKey {
+			fmt.Fprintf(d.out, "waiting for a key for V%X, continue to press one\n", state.KeyRegister)
+			return nil
+		}
+		// Waiting for the vertical blank, so finish the frame and try again
+		d.vm.TickFrame()
+		if !d.vm.PresentFrame() {
+			fmt.Fprintln(d.out, "display closed")
+			return nil
+		}
+	}
+	d.showNext()
+	return nil
+}
+
+// continueRunning runs frames at the usual speed until a breakpoint fires, the program halts or the
+// debugger is interrupted.
+func (d *Debugger) continueRunning() error {
+	atomic.StoreInt32(&d.interrupted, 0)
+	first := true
+	for {
+		if atomic.LoadInt32(&d.interrupted) == 1 {
+			fmt.Fprintln(d.out, "interrupted")
+			d.showNext()
+			return nil
+		}
+		for n := 0; n < d.vm.InstructionsPerFrame(); n++ {
+			result, stop, err := d.stepOnce(first)
+			first = false
+			if stop || err != nil {
+				return err
+			}
+			if result == StepWaiting {
+				break
+			}
+		}
+		d.vm.TickFrame()
+		if !d.vm.PresentFrame() {
+			fmt.Fprintln(d.out, "display closed")
+			return nil
+		}
+	}
+}
+
+// stepOnce executes one instruction, reporting whether execution should stop because the program
+// halted or a breakpoint fired. The first step of a command ignores a breakpoint at the PC, so that
+// the debugger can leave it.
+func (d *Debugger) stepOnce(first bool) (StepResult, bool, error) {
+	result, err := d.vm.Step()
+	if first && result == StepBreakpoint && d.vm.BreakpointHit().Breakpoint.Kind == BreakOnPC {
+		result, err = d.vm.Step()
+	}
+	if err != nil {
+		return result, true, err
+	}
+	switch result {
+	case StepHalted:
+		fmt.Fprintln(d.out, "program halted")
+		return result, true, nil
+	case StepBreakpoint:
+		fmt.Fprintln(d.out, d.vm.BreakpointHit())
+		d.showNext()
+		return result, true, nil
+	}
+	return result, false, nil
+}
+
+func (d *Debugger) addBreakpoint(args []string) error {
+	b := Breakpoint{Kind: BreakOnCondition}
+	if !strings.EqualFold(args[0], "if") {
+		address, err := parseAddress(args[0])
+		if err != nil {
+			return err
+		}
+		b = Breakpoint{Kind: BreakOnPC, Address: address}
+		args = args[1:]
+	}
+	if len(args) > 0 {
+		if !strings.EqualFold(args[0], "if") || len(args) == 1 {
+			return fmt.Errorf("usage: break [addr] [if condition]")
+		}
+		b.Condition = strings.Join(args[1:], " ")
+	}
+	return d.add(b)
+}
+
+func (d *Debugger) addWatch(read bool, target string) error {
+	if register, ok := registerNumber(target); ok && !read {
+		return d.add(Breakpoint{Kind: BreakOnRegister, Register: register})
+	}
+	if strings.EqualFold(target, "I") && !read {
+		return d.add(Breakpoint{Kind: BreakOnIndex})
+	}
+	address, err := parseAddress(target)
+	if err != nil {
+		return err
+	}
+	if read {
+		return d.add(Breakpoint{Kind: BreakOnRead, Address: address})
+	}
+	return d.add(Breakpoint{Kind: BreakOnWrite, Address: address})
+}
+
+func (d *Debugger) add(b Breakpoint) error {
+	id, err := d.vm.AddBreakpoint(b)
+	if err != nil {
+		return err
+	}
+	for _, added := range d.vm.Breakpoints() {
+		if added.ID == id {
+			fmt.Fprintln(d.out, added)
+		}
+	}
+	return nil
+}
+
+func (d *Debugger) showRegisters() {
+	state := d.vm.State()
+	for n, value := range state.Registers {
+		separator := " "
+		if n == 7 || n == 15 {
+			separator = "\n"
+		}
+		fmt.Fprintf(d.out, "V%X=%02X%s", n, value, separator)
+	}
+	fmt.Fprintf(d.out, "I=%03X PC=%03X SP=%d\n", state.IndexRegister, state.PC, state.SP)
+}
+
+func (d *Debugger) showMemory(args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf("usage: mem <addr> [len]")
+	}
+	address, err := parseAddress(args[0])
+	if err != nil {
+		return err
+	}
+	length := 16
+	if len(args) == 2 {
+		n, err := parseNumber(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid length %s", args[1])
+		}
+		length = int(n)
+	}
+	end := int(address) + length
+	if end > len(d.vm.Memory) {
+		end = len(d.vm.Memory)
+	}
+	for row := int(address); row < end; row += 16 {
+		fmt.Fprintf(d.out, "%03X ", row)
+		for n := row; n < row+16 && n < end; n++ {
+			fmt.Fprintf(d.out, " %02X", d.vm.Memory[n])
+		}
+		fmt.Fprintln(d.out)
+	}
+	return nil
+}
+
+func (d *Debugger) showDisassembly(args []string) error {
+	address := int(d.vm.PC())
+	count := 10
+	if len(args) > 0 {
+		a, err := parseAddress(args[0])
+		if err != nil {
+			return err
+		}
+		address = int(a)
+	}
+	if len(args) > 1 {
+		n, err := parseNumber(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid count %s", args[1])
+		}
+		count = int(n)
+	}
+	breakpoints := make(map[int]bool)
+	for _, b := range d.vm.Breakpoints() {
+		if b.Kind == BreakOnPC {
+			breakpoints[int(b.Address)] = true
+		}
+	}
+	for n := 0; n < count && address+1 < len(d.vm.Memory); n++ {
+		text, size := disassembleAt(d.vm.Memory, address)
+		marker := " "
+		if breakpoints[address] {
+			marker = "*"
+		}
+		fmt.Fprintf(d.out, "%s%03X  %s\n", marker, address, text)
+		address += size
+	}
+	return nil
+}
+
+func (d *Debugger) set(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: set <Vx|I|PC> <value>")
+	}
+	value, err := parseNumber(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid value %s", args[1])
+	}
+	if register, ok := registerNumber(args[0]); ok {
+		if value < 0 || value > 0xFF {
+			return fmt.Errorf("V%X only holds a byte", register)
+		}
+		d.vm.SetRegister(register, byte(value))
+		return nil
+	}
+	if value < 0 || value > 0xFFFF {
+		return fmt.Errorf("%s only holds 16 bits", args[0])
+	}
+	switch strings.ToUpper(args[0]) {
+	case "I":
+		d.vm.SetIndexRegister(uint16(value))
+	case "PC":
+		d.vm.SetPC(uint16(value))
+		d.showNext()
+	default:
+		return fmt.Errorf("unknown register %s", args[0])
+	}
+	return nil
+}
+
+// showNext prints the instruction that will execute next.
+func (d *Debugger) showNext() {
+	text, _ := disassembleAt(d.vm.Memory, int(d.vm.PC()))
+	fmt.Fprintf(d.out, "%03X  %s\n", d.vm.PC(), text)
+}
+
+func parseAddress(text string) (uint16, error) {
+	value, err := parseNumber(text)
+	if err != nil || value < 0 || value > 0xFFFF {
+		return 0, fmt.Errorf("invalid address %s", text)
+	}
+	return uint16(value), nil
+}
+
+// disassembleAt formats the instruction at address in memory, returning its size, or the word as
+// data if it isn't an instruction.
+func disassembleAt(memory []byte, address int) (string, int) {
+	analysis := &Analysis{code: memory}
+	_, op, size := analysis.instructionAt(address)
+	if op == nil {
+		if address+1 >= len(memory) {
+			return "", 2
+		}
+		return fmt.Sprintf("db 0x%02X, 0x%02X", memory[address], memory[address+1]), 2
+	}
+	return newDisassembler(analysis).formatInstruction(address), size
+}
@@ -0,0 +1,196 @@
+package chip8
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/suite"
+	"strings"
+	"testing"
+)
+
+type DebuggerTestSuite struct {
+	suite.Suite
+	vm  *VM
+	out bytes.Buffer
+}
+
+func (suite *DebuggerTestSuite) SetupTest() {
+	suite.vm = NewVM(&mockDisplay{eventType: KeyboardEvent}, MockRandom{55}, QuirksCosmacVIP)
+	suite.vm.SetClock(NewVirtualClock())
+	suite.out.Reset()
+
+	a := NewAssembler()
+	a.SetRegister(3, 0x10)
+	a.SetIndexRegister(0x300)
+	a.Label("loop")
+	a.AddToRegister(0, 1)
+	a.CallLabel("sub")
+	a.JumpTo("loop")
+	a.Label("sub")
+	a.Store(0)
+	a.Return()
+	code, err := a.Assemble()
+	suite.Require().NoError(err)
+	suite.vm.Load(code)
+}
+
+func (suite *DebuggerTestSuite) debug(commands string) string {
+	err := NewDebugger(suite.vm, strings.NewReader(commands), &suite.out).Run()
+	suite.Require().NoError(err)
+	return suite.out.String()
+}
+
+func (suite *DebuggerTestSuite) TestStepShowsTheNextInstruction() {
+	output := suite.debug("step\nstep 2\n")
+
+	suite.Contains(output, "200  LD V3, 0x10\n")
+	suite.Contains(output, "202  LD I, 0x300\n")
+	suite.Contains(output, "206  CALL 0x20A\n")
+	suite.Equal(uint16(0x206), suite.vm.PC())
+}
+
+func (suite *DebuggerTestSuite) TestStepReportsWaitingForKey() {
+	suite.vm.Load([]byte{0xF5, 0x0A})
+
+	output := suite.debug("step 2\n")
+
+	suite.Contains(output, "waiting for a key for V5")
+}
+
+func (suite *DebuggerTestSuite) TestStepCarriesOnAfterDisplayWait() {
+	a := NewAssembler()
+	a.Display(0, 0, 5)
+	a.SetRegister(1, 5)
+	a.SetRegister(2, 6)
+	code, err := a.Assemble()
+	suite.Require().NoError(err)
+	suite.vm = NewVM(&mockDisplay{eventType: KeyboardEvent}, MockRandom{55}, QuirksCosmacVIP)
+	suite.vm.SetClock(NewVirtualClock())
+	suite.vm.Load(code)
+
+	suite.debug("step\nstep\nstep\n")
+
+	suite.Equal(uint16(0x206), suite.vm.PC())
+	suite.Equal(byte(5), suite.vm.State().Registers[1])
+	suite.Equal(byte(6), suite.vm.State().Registers[2])
+}
+
+func (suite *DebuggerTestSuite) TestEmptyLineRepeatsTheLastCommand() {
+	suite.debug("step\n\n\n")
+
+	suite.Equal(uint16(0x206), suite.vm.PC())
+}
+
+func (suite *DebuggerTestSuite) TestContinueStopsAtBreakpoint() {
+	output := suite.debug("break 0x20A\ncontinue\nstack\n")
+
+	suite.Contains(output, "breakpoint 1 at 20A hit at 20A\n20A  LD [I], V0\n")
+	suite.Contains(output, " 0  208\n")
+	suite.Equal(uint16(0x20A), suite.vm.PC())
+}
+
+// interruptingDisplay interrupts the debugger after a number of frames, as Ctrl-C would.
+type interruptingDisplay struct {
+	mockDisplay
+	debugger *Debugger
+	frames   int
+}
+
+func (d *interruptingDisplay) PollEvents(keypad *Keypad) EventType {
+	d.frames--
+	if d.frames == 0 {
+		d.debugger.Interrupt()
+	}
+	return d.mockDisplay.PollEvents(keypad)
+}
+
+func (suite *DebuggerTestSuite) TestContinueCanBeInterrupted() {
+	display := &interruptingDisplay{mockDisplay: mockDisplay{eventType: KeyboardEvent}, frames: 3}
+	vm := NewVM(display, MockRandom{}, QuirksCosmacVIP)
+	vm.SetClock(NewVirtualClock())
+	vm.Load([]byte{0x70, 0x01, 0x12, 0x00})
+	display.debugger = NewDebugger(vm, strings.NewReader("continue\nregs\n"), &suite.out)
+
+	suite.Require().NoError(display.debugger.Run())
+
+	suite.Contains(suite.out.String(), "interrupted\n")
+	suite.Contains(suite.out.String(), "I=000 PC=")
+	suite.NotZero(vm.State().Registers[0])
+}
+
+func (suite *DebuggerTestSuite) TestContinueLeavesTheBreakpoint() {
+	output := suite.debug("break 0x20A\ncontinue\ncontinue\n")
+
+	suite.Equal(2, strings.Count(output, "hit at 20A"))
+	suite.Equal(byte(2), suite.vm.State().Registers[0])
+}
+
+func (suite *DebuggerTestSuite) TestConditionalBreakpoint() {
+	output := suite.debug("break if V0 == 3\ncontinue\nbreak\ndelete 1\nbreak\n")
+
+	suite.Contains(output, "breakpoint 1 when V0 == 3 hit at 204\n206  CALL 0x20A\n")
+	suite.Equal(2, strings.Count(output, "breakpoint 1 when V0 == 3\n"), "added and listed once")
+	suite.Empty(suite.vm.Breakpoints())
+}
+
+func (suite *DebuggerTestSuite) TestWatchpoints() {
+	output := suite.debug("watch 0x300\ncontinue\nwatch V0\ncontinue\nrwatch 0x301\n")
+
+	suite.Contains(output, "watchpoint 1 on write to 300\n")
+	suite.Contains(output, "watchpoint 1 on write to 300 hit at 20A: 00 -> 01\n20C  RET\n")
+	suite.Contains(output, "watchpoint 2 on V0 hit at 204: 01 -> 02\n")
+	suite.Contains(output, "watchpoint 3 on read of 301\n")
+}
+
+func (suite *DebuggerTestSuite) TestWatchIndex() {
+	output := suite.debug("watch I\ncontinue\n")
+
+	suite.Contains(output, "watchpoint 1 on I hit at 202: 000 -> 300\n")
+}
+
+func (suite *DebuggerTestSuite) TestRegsAndSet() {
+	output := suite.debug("set V3 0x10\nset I 0x2AB\nset PC 0x204\nregs\n")
+
+	suite.Contains(output, "V0=00 V1=00 V2=00 V3=10 V4=00 V5=00 V6=00 V7=00\n")
+	suite.Contains(output, "I=2AB PC=204 SP=0\n")
+	suite.Equal(uint16(0x204), suite.vm.PC())
+}
+
+func (suite *DebuggerTestSuite) TestMemAndDisasm() {
+	output := suite.debug("mem 0x200 4\ndisasm 0x204 2\n")
+
+	suite.Contains(output, "200  63 10 A3 00\n")
+	suite.Contains(output, " 204  ADD V0, 0x01\n 206  CALL 0x20A\n")
+}
+
+func (suite *DebuggerTestSuite) TestTraceOn() {
+	output := suite.debug("trace on\nstep\ntrace off\nstep\n")
+
+	suite.Contains(output, "200  6310  LD V3, 0x10")
+	suite.NotContains(output, "202  A300")
+}
+
+func (suite *DebuggerTestSuite) TestTraceFlow() {
+	output := suite.debug("trace flow\nstep 4\n")
+
+	suite.NotContains(output, "200  6310")
+	suite.Contains(output, "206  220A  CALL 0x20A")
+}
+
+func (suite *DebuggerTestSuite) TestFaultIsReported() {
+	suite.vm.Load([]byte{0x00, 0xEE})
+
+	output := suite.debug("step\n")
+
+	suite.Contains(output, "stack underflow at 200 executing 00EE")
+}
+
+func (suite *DebuggerTestSuite) TestUnknownCommand() {
+	output := suite.debug("frobnicate\nquit\nstep\n")
+
+	suite.Contains(output, "unknown command frobnicate, try help\n")
+	suite.Equal(uint16(0x200), suite.vm.PC())
+}
+
+func TestDebuggerSuite(t *testing.T) {
+	suite.Run(t, new(DebuggerTestSuite))
+}
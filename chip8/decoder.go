@@ -0,0 +1,82 @@
+package chip8
+
+// opcode describes every instruction word w for which w&mask == pattern. The syntax is how it is
+// written for the assembler, with the operands named as in sourceInstructions; x is the X nibble as
+// a number rather than a register.
+type opcode struct {
+	name     string
+	syntax   string
+	mask     uint16
+	pattern  uint16
+	function func(Instruction) error
+}
+
+var opcodes = []opcode{
+	{"ClearScreen", "CLS", 0xFFFF, 0x00E0, Instruction.clearScreen},
+	{"Return", "RET", 0xFFFF, 0x00EE, Instruction.opReturn},
+	{"ScrollDown", "SCD n", 0xFFF0, 0x00C0, Instruction.scrollDown},
+	{"ScrollRight", "SCR", 0xFFFF, 0x00FB, Instruction.scrollRight},
+	{"ScrollLeft", "SCL", 0xFFFF, 0x00FC, Instruction.scrollLeft},
+	{"Exit", "EXIT", 0xFFFF, 0x00FD, Instruction.exit},
+	{"LowResolution", "LOW", 0xFFFF, 0x00FE, Instruction.lowResolution},
+	{"HighResolution", "HIGH", 0xFFFF, 0x00FF, Instruction.highResolution},
+	{"Jump", "JP nnn", 0xF000, 0x1000, Instruction.jump},
+	{"Subroutine", "CALL nnn", 0xF000, 0x2000, Instruction.subroutine},
+	{"SkipIfEqual", "SE Vx, nn", 0xF000, 0x3000, Instruction.skipIfEqual},
+	{"SkipIfNotEqual", "SNE Vx, nn", 0xF000, 0x4000, Instruction.skipIfNotEqual},
+	{"SkipIfRegistersEqual", "SE Vx, Vy", 0xF00F, 0x5000, Instruction.skipIfRegistersEqual},
+	{"SaveRange", "SAVE Vx, Vy", 0xF00F, 0x5002, Instruction.saveRange},
+	{"LoadRange", "LOAD Vx, Vy", 0xF00F, 0x5003, Instruction.loadRange},
+	{"SetRegister", "LD Vx, nn", 0xF000, 0x6000, Instruction.setRegister},
+	{"AddToRegister", "ADD Vx, nn", 0xF000, 0x7000, Instruction.addToRegister},
+	{"SetVxToVy", "LD Vx, Vy", 0xF00F, 0x8000, Instruction.setVxToVy},
+	{"Or", "OR Vx, Vy", 0xF00F, 0x8001, Instruction.or},
+	{"And", "AND Vx, Vy", 0xF00F, 0x8002, Instruction.and},
+	{"Xor", "XOR Vx, Vy", 0xF00F, 0x8003, Instruction.xOr},
+	{"AddToVx", "ADD Vx, Vy", 0xF00F, 0x8004, Instruction.addToVx},
+	{"SubtractFromVx", "SUB Vx, Vy", 0xF00F, 0x8005, Instruction.subtractFromVx},
+	{"ShiftRight", "SHR Vx, Vy", 0xF00F, 0x8006, Instruction.shiftRight},
+	{"SubtractFromVy", "SUBN Vx, Vy", 0xF00F, 0x8007, Instruction.subtractFromVy},
+	{"ShiftLeft", "SHL Vx, Vy", 0xF00F, 0x800E, Instruction.shiftLeft},
+	{"SkipIfRegistersNotEqual", "SNE Vx, Vy", 0xF000, 0x9000, Instruction.skipIfRegistersNotEqual},
+	{"SetIndexRegister", "LD I, nnn", 0xF000, 0xA000, Instruction.setIndexRegister},
+	{"JumpWithOffset", "JP V0, nnn", 0xF000, 0xB000, Instruction.jumpWithOffset},
+	{"OpRandom", "RND Vx, nn", 0xF000, 0xC000, Instruction.opRandom},
+	{"Display", "DRW Vx, Vy, n", 0xF000, 0xD000, Instruction.opDisplay},
+	{"SkipIfKey", "SKP Vx", 0xF0FF, 0xE09E, Instruction.skipIfKeyPressed},
+	{"SkipIfNotKey", "SKNP Vx", 0xF0FF, 0xE0A1, Instruction.skipIfKeyNotPressed},
+	{"LongSetIndexRegister", "LD I, LONG nnnn", 0xFFFF, 0xF000, Instruction.longSetIndexRegister},
+	{"SelectPlanes", "PLANE x", 0xF0FF, 0xF001, Instruction.selectPlanes},
+	{"LoadAudioPattern", "AUDIO", 0xFFFF, 0xF002, Instruction.loadAudioPattern},
+	{"GetDelayTimer", "LD Vx, DT", 0xF0FF, 0xF007, Instruction.getDelayTimer},
+	{"GetKey", "LD Vx, K", 0xF0FF, 0xF00A, Instruction.getKey},
+	{"SetDelayTimer", "LD DT, Vx", 0xF0FF, 0xF015, Instruction.setDelayTimer},
+	{"SetSoundTimer", "LD ST, Vx", 0xF0FF, 0xF018, Instruction.setSoundTimer},
+	{"AddToIndex", "ADD I, Vx", 0xF0FF, 0xF01E, Instruction.addToIndex},
+	{"FontChar", "LD F, Vx", 0xF0FF, 0xF029, Instruction.fontChar},
+	{"LargeFontChar", "LD HF, Vx", 0xF0FF, 0xF030, Instruction.largeFontChar},
+	{"SetPitch", "PITCH Vx", 0xF0FF, 0xF03A, Instruction.setPitch},
+	{"Bcd", "LD B, Vx", 0xF0FF, 0xF033, Instruction.bcd},
+	{"Store", "LD [I], Vx", 0xF0FF, 0xF055, Instruction.store},
+	{"Load", "LD Vx, [I]", 0xF0FF, 0xF065, Instruction.load},
+	{"StoreFlags", "LD R, Vx", 0xF0FF, 0xF075, Instruction.storeFlags},
+	{"LoadFlags", "LD Vx, R", 0xF0FF, 0xF085, Instruction.loadFlags},
+}
+
+// decodeTable maps every possible instruction word to its opcode, or nil if it isn't one.
+var decodeTable [0x10000]*opcode
+
+func init() {
+	for word := 0; word < len(decodeTable); word++ {
+		for n := range opcodes {
+			if uint16(word)&opcodes[n].mask == opcodes[n].pattern {
+				decodeTable[word] = &opcodes[n]
+				break
+			}
+		}
+	}
+}
+
+func decode(instr uint16) *opcode {
+	return decodeTable[instr]
+}
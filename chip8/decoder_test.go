@@ -0,0 +1,44 @@
+package chip8
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type DecoderTestSuite struct {
+	suite.Suite
+}
+
+func (suite *DecoderTestSuite) TestDecodeExactOpcodes() {
+	suite.Equal("ClearScreen", decode(0x00E0).name)
+	suite.Equal("Return", decode(0x00EE).name)
+}
+
+func (suite *DecoderTestSuite) TestDecodeByFirstNibble() {
+	suite.Equal("Jump", decode(0x1234).name)
+	suite.Equal("SetIndexRegister", decode(0xAFFF).name)
+	suite.Equal("Display", decode(0xD125).name)
+}
+
+func (suite *DecoderTestSuite) TestDecodeByLastNibble() {
+	suite.Equal("SetVxToVy", decode(0x8120).name)
+	suite.Equal("ShiftRight", decode(0x8126).name)
+	suite.Equal("ShiftLeft", decode(0x812E).name)
+}
+
+func (suite *DecoderTestSuite) TestDecodeBySecondByte() {
+	suite.Equal("SkipIfKey", decode(0xE39E).name)
+	suite.Equal("SkipIfNotKey", decode(0xE3A1).name)
+	suite.Equal("Load", decode(0xFA65).name)
+}
+
+func (suite *DecoderTestSuite) TestUnknownOpcodesDecodeToNil() {
+	suite.Nil(decode(0x0123))
+	suite.Nil(decode(0x8128))
+	suite.Nil(decode(0xE100))
+	suite.Nil(decode(0xF0FF))
+}
+
+func TestDecoderTestSuite(t *testing.T) {
+	suite.Run(t, new(DecoderTestSuite))
+}
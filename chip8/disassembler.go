@@ -0,0 +1,132 @@
+package chip8
+
+import (
+	"fmt"
+	"strings"
+)
+
+const bytesPerDataLine = 8
+
+type disassembler struct {
+	analysis *Analysis
+	labels   map[int]bool
+}
+
+func newDisassembler(analysis *Analysis) *disassembler {
+	return &disassembler{analysis: analysis, labels: make(map[int]bool)}
+}
+
+// Disassemble turns a program loaded at origin back into source that AssembleSource accepts. Code is
+// found by following every path from the origin, jumps and calls get generated labels, and bytes that
+// no path reaches are written as data. AssembleSource always assembles for 0x200, so for any other
+// origin the labels are written as constants, which assemble back to the same bytes.
+func Disassemble(code []byte, origin uint16) string {
+	d := newDisassembler(Analyze(code, origin))
+
+	lines := d.layout()
+	for _, line := range lines {
+		if d.analysis.targets[line.address] {
+			d.labels[line.address] = true
+		}
+	}
+
+	var source strings.Builder
+	if origin != ProgramStart {
+		fmt.Fprintf(&source, "; loaded at 0x%03X\n", origin)
+	}
+	for _, line := range lines {
+		if d.labels[line.address] && origin != ProgramStart {
+			fmt.Fprintf(&source, "%s = 0x%03X\n", d.labelName(line.address), line.address)
+		} else if d.labels[line.address] {
+			fmt.Fprintf(&source, "%s:\n", d.labelName(line.address))
+		}
+		fmt.Fprintf(&source, "\t%-23s ; %03X\n", d.format(line), line.address)
+	}
+	return source.String()
+}
+
+type disassemblyLine struct {
+	address     int
+	instruction bool
+	bytes       []byte
+}
+
+// layout splits the program into lines of code and data, breaking data at anything that needs a label.
+func (d *disassembler) layout() []disassemblyLine {
+	var lines []disassemblyLine
+	a := d.analysis
+	address := int(a.Origin)
+	end := address + len(a.code)
+	for address < end {
+		offset := address - int(a.Origin)
+		if size, ok := a.instructions[address]; ok {
+			lines = append(lines, disassemblyLine{address: address, instruction: true, bytes: a.code[offset : offset+size]})
+			address += size
+			continue
+		}
+		length := 1
+		for address+length < end && length < bytesPerDataLine {
+			if _, ok := a.instructions[address+length]; ok || a.targets[address+length] {
+				break
+			}
+			length++
+		}
+		lines = append(lines, disassemblyLine{address: address, bytes: a.code[offset : offset+length]})
+		address += length
+	}
+	return lines
+}
+
+func (d *disassembler) labelName(address int) string {
+	return fmt.Sprintf("L%03X", address)
+}
+
+func (d *disassembler) addressOperand(address int, digits int) string {
+	if d.labels[address] {
+		return d.labelName(address)
+	}
+	return fmt.Sprintf("0x%0*X", digits, address)
+}
+
+func (d *disassembler) format(line disassemblyLine) string {
+	if !line.instruction {
+		values := make([]string, len(line.bytes))
+		for n, b := range line.bytes {
+			values[n] = fmt.Sprintf("0x%02X", b)
+		}
+		return "db " + strings.Join(values, ", ")
+	}
+
+	return d.formatInstruction(line.address)
+}
+
+// formatInstruction writes the instruction at address as source, using labels where there are any.
+func (d *disassembler) formatInstruction(address int) string {
+	word, _ := d.analysis.word(address)
+	i := newInstruction(uint16(address), word, nil)
+	mnemonic, operands, _ := strings.Cut(decode(word).syntax, " ")
+	if operands == "" {
+		return mnemonic
+	}
+	formatted := strings.Split(operands, ", ")
+	for n, operand := range formatted {
+		switch operand {
+		case "Vx":
+			formatted[n] = fmt.Sprintf("V%X", i.vx)
+		case "Vy":
+			formatted[n] = fmt.Sprintf("V%X", i.vy)
+		case "x":
+			formatted[n] = fmt.Sprintf("%d", i.vx)
+		case "n":
+			formatted[n] = fmt.Sprintf("%d", i.opCode2)
+		case "nn":
+			formatted[n] = fmt.Sprintf("0x%02X", i.secondByte)
+		case "nnn":
+			formatted[n] = d.addressOperand(int(i.address), 3)
+		case "LONG nnnn":
+			long, _ := d.analysis.word(address + 2)
+			formatted[n] = "LONG " + d.addressOperand(int(long), 4)
+		}
+	}
+	return mnemonic + " " + strings.Join(formatted, ", ")
+}
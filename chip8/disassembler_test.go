@@ -0,0 +1,97 @@
+package chip8
+
+import (
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+type DisassemblerTestSuite struct {
+	suite.Suite
+}
+
+func (suite *DisassemblerTestSuite) TestCodeLabelsAndData() {
+	a := NewAssembler()
+	a.Label("main")
+	a.SetIndexToLabel("sprite")
+	a.SetRegister(0, 0x10)
+	a.Display(0, 1, 2)
+	a.CallLabel("wait")
+	a.JumpTo("main")
+	a.Label("wait")
+	a.SkipIfKeyPressed(3)
+	a.JumpTo("wait")
+	a.Return()
+	a.Label("sprite")
+	a.Data([]byte{0xF0, 0x90})
+	code, err := a.Assemble()
+	suite.Require().NoError(err)
+
+	source := Disassemble(code, ProgramStart)
+
+	suite.Equal(`L200:
+	LD I, L210              ; 200
+	LD V0, 0x10             ; 202
+	DRW V0, V1, 2           ; 204
+	CALL L20A               ; 206
+	JP L200                 ; 208
+L20A:
+	SKP V3                  ; 20A
+	JP L20A                 ; 20C
+	RET                     ; 20E
+L210:
+	db 0xF0, 0x90           ; 210
+`, source)
+}
+
+func (suite *DisassemblerTestSuite) TestUnreachedBytesAreData() {
+	source := Disassemble([]byte{0x00, 0xFD, 0x60, 0x01, 0x12}, ProgramStart)
+
+	suite.Equal("\tEXIT                    ; 200\n\tdb 0x60, 0x01, 0x12     ; 202\n", source)
+}
+
+func (suite *DisassemblerTestSuite) TestSkipOverLongIndexLoad() {
+	a := NewAssembler()
+	a.SkipIfEqual(0, 0)
+	a.LongSetIndexRegister(0x1234)
+	a.Exit()
+	code, _ := a.Assemble()
+
+	source := Disassemble(code, ProgramStart)
+
+	suite.Contains(source, "LD I, LONG 0x1234")
+	suite.Contains(source, "EXIT")
+	suite.NotContains(source, "db")
+}
+
+func (suite *DisassemblerTestSuite) TestOriginOtherThanProgramStart() {
+	source := Disassemble([]byte{0x13, 0x00}, 0x300)
+
+	suite.Equal("; loaded at 0x300\nL300 = 0x300\n\tJP L300                 ; 300\n", source)
+
+	for _, origin := range []uint16{0x100, 0x300} {
+		code := []byte{0x60, 0x05, 0x10 | byte(origin>>8), 0x02}
+		reassembled, err := AssembleSource(strings.NewReader(Disassemble(code, origin)))
+
+		suite.NoError(err, "origin %03X", origin)
+		suite.Equal(code, reassembled, "origin %03X", origin)
+	}
+}
+
+func (suite *DisassemblerTestSuite) TestRoundTrip() {
+	for _, rom := range []string{"../IBM-Logo.ch8", "../roms/BC_test.ch8", "../test_opcode.ch8"} {
+		code, err := ioutil.ReadFile(rom)
+		suite.Require().NoError(err)
+
+		source := Disassemble(code, ProgramStart)
+		reassembled, err := AssembleSource(strings.NewReader(source))
+
+		suite.NoError(err, rom)
+		suite.Equal(code, reassembled, rom)
+	}
+}
+
+func TestDisassemblerSuite(t *testing.T) {
+	suite.Run(t, new(DisassemblerTestSuite))
+}
@@ -1,55 +1,204 @@
 package chip8
 
+const lowResolutionWidth = 64
+const lowResolutionHeight = 32
+const highResolutionWidth = 128
+const highResolutionHeight = 64
+
+// The XO-CHIP has two bitplanes, so each pixel holds a colour from 0 to 3:
+// bit 0 is set by plane 1 and bit 1 by plane 2.
+const planeCount = 2
+const defaultPlanes = 1
+const allPlanes = 3
+
 type DisplayBuffer struct {
-	Pixels   [][]byte
-	overflow bool
+	Pixels         [][]byte
+	planes         byte
+	overflow       bool
+	wrap           bool
+	highResolution bool
+	dirty          bool
 }
 
 func NewDisplayBuffer() *DisplayBuffer {
 	db := new(DisplayBuffer)
-	db.Pixels = make([][]byte, 32)
-	for i := range db.Pixels {
-		db.Pixels[i] = make([]byte, 64)
-	}
+	db.resize(lowResolutionWidth, lowResolutionHeight)
+	db.planes = defaultPlanes
 	db.overflow = false
 	return db
 }
 
+func (d *DisplayBuffer) resize(width int, height int) {
+	d.Pixels = make([][]byte, height)
+	for i := range d.Pixels {
+		d.Pixels[i] = make([]byte, width)
+	}
+	d.dirty = true
+}
+
+func (d *DisplayBuffer) Width() int {
+	return len(d.Pixels[0])
+}
+
+func (d *DisplayBuffer) Height() int {
+	return len(d.Pixels)
+}
+
+func (d *DisplayBuffer) HighResolution() bool {
+	return d.highResolution
+}
+
+// SetHighResolution switches between 64x32 and 128x64 pixels, clearing the screen.
+func (d *DisplayBuffer) SetHighResolution(highResolution bool) {
+	d.highResolution = highResolution
+	if highResolution {
+		d.resize(highResolutionWidth, highResolutionHeight)
+	} else {
+		d.resize(lowResolutionWidth, lowResolutionHeight)
+	}
+}
+
+func (d *DisplayBuffer) SetSpriteWrapping(wrap bool) {
+	d.wrap = wrap
+}
+
+// SelectPlanes chooses which bitplanes are drawn, cleared and scrolled, as a mask from 0 to 3.
+func (d *DisplayBuffer) SelectPlanes(planes byte) {
+	d.planes = planes & allPlanes
+}
+
+func (d *DisplayBuffer) SelectedPlanes() byte {
+	return d.planes
+}
+
+// SelectedPlaneCount is the number of sprites a draw consumes, one for each selected plane.
+func (d *DisplayBuffer) SelectedPlaneCount() int {
+	count := 0
+	for plane := 0; plane < planeCount; plane++ {
+		if d.planes&(1<<plane) != 0 {
+			count++
+		}
+	}
+	return count
+}
+
 func (d *DisplayBuffer) ClearScreen() {
 	for i := range d.Pixels {
-		for j := range d.Pixels[i] {
-			d.Pixels[i][j] = 0
+		clearRow(d.Pixels[i], d.planes)
+	}
+	d.dirty = true
+}
+
+// DrawSprite draws an 8 pixel wide sprite, one byte per row, and reports whether any pixel was turned off.
+// When several planes are selected the sprite for each plane follows the previous one in memory.
+func (d *DisplayBuffer) DrawSprite(startAddress uint16, heightInPixels byte, x byte, y byte, memory []byte) bool {
+	d.overflow = false
+	address := int(startAddress)
+	for plane := 0; plane < planeCount; plane++ {
+		mask := byte(1 << plane)
+		if d.planes&mask == 0 {
+			continue
+		}
+		yPos := y
+		for n := 0; n < int(heightInPixels); n++ {
+			d.drawPlaneByte(memory[address], x, yPos, mask)
+			address++
+			yPos++
 		}
 	}
+
+	d.dirty = true
+	return d.overflow
 }
 
-func (d *DisplayBuffer) DrawSprite(startAddress uint16, heightInPixels byte, x byte, y byte, memory [4096]byte) bool {
-	yPos := y
-	address := startAddress
-	for n := 0; n < int(heightInPixels); n++ {
-		value := memory[address]
-		address++
-		d.drawByte(value, x, yPos)
-		yPos++
+// DrawLargeSprite draws a 16x16 pixel sprite, two bytes per row, and reports whether any pixel was turned off.
+func (d *DisplayBuffer) DrawLargeSprite(startAddress uint16, x byte, y byte, memory []byte) bool {
+	d.overflow = false
+	address := int(startAddress)
+	for plane := 0; plane < planeCount; plane++ {
+		mask := byte(1 << plane)
+		if d.planes&mask == 0 {
+			continue
+		}
+		yPos := y
+		for n := 0; n < 16; n++ {
+			d.drawPlaneByte(memory[address], x, yPos, mask)
+			d.drawPlaneByte(memory[address+1], x+8, yPos, mask)
+			address += 2
+			yPos++
+		}
 	}
 
+	d.dirty = true
 	return d.overflow
 }
 
 func (d *DisplayBuffer) drawByte(value byte, xpos byte, ypos byte) {
+	d.drawPlaneByte(value, xpos, ypos, defaultPlanes)
+}
+
+func (d *DisplayBuffer) drawPlaneByte(value byte, xpos byte, ypos byte, plane byte) {
+	width, height := d.Width(), d.Height()
 	for index := 7; index >= 0; index-- {
-		//fmt.Printf("Drawing at pos %d, %d\n", xpos, ypos)
 		bit := GetValueAtPosition(index, value)
-		if bit == 1 && xpos < 64 && ypos < 32 {
-			if d.Pixels[ypos][xpos] == 1 {
-				d.Pixels[ypos][xpos] = 0
+		x, y := int(xpos)+7-index, int(ypos)
+		if d.wrap {
+			x, y = x%width, y%height
+		}
+		if bit == 1 && x < width && y < height {
+			if d.Pixels[y][x]&plane != 0 {
 				// Should set VF to 1
 				d.overflow = true
-			} else {
-				d.Pixels[ypos][xpos] = 1
 			}
+			d.Pixels[y][x] ^= plane
+		}
+	}
+}
+
+// ScrollDown moves the selected planes down by n pixels, leaving blank rows at the top.
+func (d *DisplayBuffer) ScrollDown(n int) {
+	height := d.Height()
+	for y := height - 1; y >= 0; y-- {
+		if y >= n {
+			copyRow(d.Pixels[y], d.Pixels[y-n], d.planes)
+		} else {
+			clearRow(d.Pixels[y], d.planes)
 		}
-		xpos += 1
+	}
+	d.dirty = true
+}
+
+// ScrollRight moves the selected planes right by n pixels, leaving blank columns on the left.
+func (d *DisplayBuffer) ScrollRight(n int) {
+	for _, row := range d.Pixels {
+		for x := len(row) - 1; x >= n; x-- {
+			row[x] = row[x]&^d.planes | row[x-n]&d.planes
+		}
+		clearRow(row[:n], d.planes)
+	}
+	d.dirty = true
+}
+
+// ScrollLeft moves the selected planes left by n pixels, leaving blank columns on the right.
+func (d *DisplayBuffer) ScrollLeft(n int) {
+	width := d.Width()
+	for _, row := range d.Pixels {
+		copyRow(row, row[n:], d.planes)
+		clearRow(row[width-n:], d.planes)
+	}
+	d.dirty = true
+}
+
+// copyRow copies the given planes from src to dst, which may only overlap if dst starts first.
+func copyRow(dst []byte, src []byte, planes byte) {
+	for x := 0; x < len(dst) && x < len(src); x++ {
+		dst[x] = dst[x]&^planes | src[x]&planes
+	}
+}
+
+func clearRow(row []byte, planes byte) {
+	for x := range row {
+		row[x] &^= planes
 	}
 }
 
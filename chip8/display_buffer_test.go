@@ -40,11 +40,25 @@ func (suite *DisplayBufferTestSuite) TestSpriteIsClipped_WhenEdgeOfScreen() {
 
 	memory := [4096]byte{0xFF, 0x00, 0xFF, 0x00, 0xFF, 0x00, 0xFF, 0x00}
 
-	displayBuffer.DrawSprite(0, 1, 63, 31, memory)
+	displayBuffer.DrawSprite(0, 1, 63, 31, memory[:])
 
 	suite.Equal(false, verifyAllBlank(displayBuffer))
 }
 
+func (suite *DisplayBufferTestSuite) TestSpriteIsWrapped_WhenWrappingEnabled() {
+	displayBuffer := NewDisplayBuffer()
+	displayBuffer.SetSpriteWrapping(true)
+
+	memory := [4096]byte{0xFF, 0xFF}
+
+	displayBuffer.DrawSprite(0, 2, 60, 31, memory[:])
+
+	suite.Equal(uint8(1), displayBuffer.GetPixelAt(63, 31))
+	suite.Equal(uint8(1), displayBuffer.GetPixelAt(0, 31))
+	suite.Equal(uint8(1), displayBuffer.GetPixelAt(3, 0))
+	suite.Equal(uint8(0), displayBuffer.GetPixelAt(4, 0))
+}
+
 func (suite *DisplayBufferTestSuite) TestScreenIsClipped() {
 	displayBuffer := NewDisplayBuffer()
 
@@ -117,6 +131,93 @@ func (suite *DisplayBufferTestSuite) TestXorPattern() {
 	suite.Equal(uint8(1), displayBuffer.GetPixelAt(7, 0))
 }
 
+func (suite *DisplayBufferTestSuite) TestHighResolutionResizesScreen() {
+	displayBuffer := NewDisplayBuffer()
+	suite.Equal(64, displayBuffer.Width())
+	suite.Equal(32, displayBuffer.Height())
+
+	displayBuffer.SetHighResolution(true)
+	suite.Equal(128, displayBuffer.Width())
+	suite.Equal(64, displayBuffer.Height())
+	suite.True(displayBuffer.HighResolution())
+
+	displayBuffer.drawByte(uint8(0b00000001), 120, 63)
+	suite.Equal(uint8(1), displayBuffer.GetPixelAt(127, 63))
+
+	displayBuffer.SetHighResolution(false)
+	suite.Equal(64, displayBuffer.Width())
+	suite.Equal(true, verifyAllBlank(displayBuffer))
+}
+
+func (suite *DisplayBufferTestSuite) TestCollisionIsOnlyReportedForTheCurrentSprite() {
+	displayBuffer := NewDisplayBuffer()
+	memory := []byte{0xFF}
+
+	suite.False(displayBuffer.DrawSprite(0, 1, 0, 0, memory))
+	suite.True(displayBuffer.DrawSprite(0, 1, 0, 0, memory))
+	suite.False(displayBuffer.DrawSprite(0, 1, 0, 0, memory))
+}
+
+func (suite *DisplayBufferTestSuite) TestDrawLargeSprite() {
+	displayBuffer := NewDisplayBuffer()
+	memory := make([]byte, 32)
+	memory[0] = 0x80
+	memory[1] = 0x01
+	memory[31] = 0x01
+
+	displayBuffer.DrawLargeSprite(0, 10, 5, memory)
+
+	suite.Equal(uint8(1), displayBuffer.GetPixelAt(10, 5))
+	suite.Equal(uint8(1), displayBuffer.GetPixelAt(25, 5))
+	suite.Equal(uint8(1), displayBuffer.GetPixelAt(25, 20))
+	suite.Equal(uint8(0), displayBuffer.GetPixelAt(24, 20))
+}
+
+func (suite *DisplayBufferTestSuite) TestScrollDown() {
+	displayBuffer := NewDisplayBuffer()
+	displayBuffer.drawByte(uint8(0b10000000), 3, 0)
+	displayBuffer.drawByte(uint8(0b10000000), 4, 31)
+
+	displayBuffer.ScrollDown(2)
+
+	suite.Equal(uint8(0), displayBuffer.GetPixelAt(3, 0))
+	suite.Equal(uint8(1), displayBuffer.GetPixelAt(3, 2))
+	suite.Equal(uint8(0), displayBuffer.GetPixelAt(4, 31))
+}
+
+func (suite *DisplayBufferTestSuite) TestScrollRight() {
+	displayBuffer := NewDisplayBuffer()
+	displayBuffer.drawByte(uint8(0b10000001), 0, 1)
+
+	displayBuffer.ScrollRight(4)
+
+	suite.Equal([]byte{0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 1}, displayBuffer.Pixels[1][:12])
+}
+
+func (suite *DisplayBufferTestSuite) TestScrollLeft() {
+	displayBuffer := NewDisplayBuffer()
+	displayBuffer.drawByte(uint8(0b10000001), 4, 1)
+	displayBuffer.drawByte(uint8(0b00000001), 56, 1)
+
+	displayBuffer.ScrollLeft(4)
+
+	suite.Equal(uint8(1), displayBuffer.GetPixelAt(0, 1))
+	suite.Equal(uint8(1), displayBuffer.GetPixelAt(7, 1))
+	suite.Equal(uint8(1), displayBuffer.GetPixelAt(59, 1))
+	suite.Equal([]byte{0, 0, 0, 0}, displayBuffer.Pixels[1][60:])
+}
+
+func (suite *DisplayBufferTestSuite) TestScrollOnlyMovesSelectedPlanes() {
+	displayBuffer := NewDisplayBuffer()
+	displayBuffer.SelectPlanes(3)
+	displayBuffer.DrawSprite(0, 1, 0, 0, []byte{0x80, 0x80})
+
+	displayBuffer.SelectPlanes(2)
+	displayBuffer.ScrollRight(4)
+
+	suite.Equal([]byte{1, 0, 0, 0, 2}, displayBuffer.Pixels[0][:5])
+}
+
 func TestDisplayBufferSuite(t *testing.T) {
 	suite.Run(t, new(DisplayBufferTestSuite))
 }
@@ -9,8 +9,8 @@ const (
 )
 
 type DisplayInterface interface {
-	ClearScreen()
-	DrawSprite(address uint16, numberOfBytes byte, x byte, y byte, memory [4096]byte) bool
-	PollEvents() EventType
-	GetKey() int
+	// Render shows the contents of the buffer, and is called once per frame when it has changed.
+	Render(buffer *DisplayBuffer)
+	// PollEvents handles the window's events, pressing and releasing the keys on keypad.
+	PollEvents(keypad *Keypad) EventType
 }
@@ -0,0 +1,47 @@
+package chip8
+
+import "fmt"
+
+type VMErrorKind int
+
+const (
+	StackOverflow VMErrorKind = iota
+	StackUnderflow
+	MemoryOutOfBounds
+	UnknownOpcode
+	PCOutOfRange
+)
+
+func (k VMErrorKind) String() string {
+	names := map[VMErrorKind]string{
+		StackOverflow:     "stack overflow",
+		StackUnderflow:    "stack underflow",
+		MemoryOutOfBounds: "memory out of bounds",
+		UnknownOpcode:     "unknown opcode",
+		PCOutOfRange:      "PC out of range",
+	}
+	return names[k]
+}
+
+// VMError describes why the VM stopped, along with the state of the CPU when it did.
+type VMError struct {
+	Kind          VMErrorKind
+	PC            uint16
+	Opcode        uint16
+	Registers     [16]byte
+	IndexRegister uint16
+}
+
+func (e *VMError) Error() string {
+	return fmt.Sprintf("%s at %03X executing %04X", e.Kind, e.PC, e.Opcode)
+}
+
+func (v *VM) newError(kind VMErrorKind, pc uint16, opcode uint16) *VMError {
+	return &VMError{
+		Kind:          kind,
+		PC:            pc,
+		Opcode:        opcode,
+		Registers:     v.registers,
+		IndexRegister: v.indexRegister,
+	}
+}
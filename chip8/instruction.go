@@ -1,8 +1,7 @@
 package chip8
 
-import "fmt"
-
 type Instruction struct {
+	pc         uint16
 	instr      uint16
 	opCode     byte
 	vx         byte
@@ -13,254 +12,331 @@ type Instruction struct {
 	vm         *VM
 }
 
-type Opcode struct {
-	name     string
-	function instruction
-}
-
-type instruction func()
-
-const ClearScreen = 0x00E0
-const Return = 0x00EE
-const Jump = 0x1
-const Subroutine = 0x2
-const SkipIfEqual = 0x3
-const SkipIfNotEqual = 0x4
-const SkipIfRegistersEqual = 0x5
-const SkipIfRegistersNotEqual = 0x9
-const SetRegister = 0x6
-const AddToRegister = 0x7
-const BitwiseOperations = 0x8
-const SetIndexRegister = 0xA
-const JumpWithOffset = 0xB
-const OpRandom = 0xC
-const Display = 0xD
-const SkipIfKey = 0xE
-const FurtherOperations = 0xF
-
-const setVxToVy = 0x00
-const binaryOr = 0x01
-const binaryAnd = 0x02
-const logicalXor = 0x03
-const addToVx = 0x04
-const subtractFromVx = 0x05
-const shiftRight = 0x06
-const subtractFromVy = 0x07
-const shiftLeft = 0x0E
-
-const Bcd = 0x33
-const FontChar = 0x29
-const GetKey = 0x0A
-const AddToIndex = 0x1E
-const Store = 0x55
-const Load = 0x65
-const GetDelayTimer = 0x07
-const SetDelayTimer = 0x15
-const SetSoundTimer = 0x18
-
-func NewInstruction(instr uint16, vm *VM) *Instruction {
-	i := new(Instruction)
-	i.vm = vm
-	i.extractNibbles(instr)
-	return i
-}
-
-func (i *Instruction) extractNibbles(instr uint16) {
-	i.instr = instr
-	i.opCode = extractNibble(instr)
-	i.vx = getRightNibble(extractFirstByte(instr))
-	i.secondByte = extractSecondByte(instr)
-	i.vy = getLeftNibble(i.secondByte)
-	i.opCode2 = getRightNibble(i.secondByte)
-	i.address = extract12BitNumber(instr)
-}
-
-func (i *Instruction) execute() {
-	if i.instr == ClearScreen {
-		i.clearScreen()
-	} else if i.instr == Return {
-		i.opReturn()
-	} else {
-		name := i.getOpcodeName()
-		if name != "" {
-			fmt.Printf("> %s\n", name)
-		}
+func newInstruction(pc uint16, instr uint16, vm *VM) Instruction {
+	secondByte := extractSecondByte(instr)
+	return Instruction{
+		pc:         pc,
+		instr:      instr,
+		opCode:     extractNibble(instr),
+		vx:         getRightNibble(extractFirstByte(instr)),
+		vy:         getLeftNibble(secondByte),
+		opCode2:    getRightNibble(secondByte),
+		secondByte: secondByte,
+		address:    extract12BitNumber(instr),
+		vm:         vm,
+	}
+}
+
+func (i Instruction) fault(kind VMErrorKind) error {
+	return i.vm.newError(kind, i.pc, i.instr)
+}
 
-		function := i.getInstructionFromOpcode()
-		if function == nil {
-			i.print()
-		} else {
-			function()
+func (i Instruction) unknownOpcode() error {
+	switch i.vm.unknownOpcodePolicy {
+	case SkipUnknownOpcode:
+		return nil
+	case TrapUnknownOpcode:
+		if i.vm.trapHandler != nil {
+			return i.vm.trapHandler(i.vm, i.instr)
 		}
 	}
+	return i.fault(UnknownOpcode)
+}
 
+func (i Instruction) checkMemory(address uint16, length int) error {
+	if int(address)+length > len(i.vm.Memory) {
+		return i.fault(MemoryOutOfBounds)
+	}
+	return nil
 }
 
-func (i *Instruction) getInstructionFromOpcode() instruction {
-	opCodeFunctions := i.primaryOpcodes()
-	return opCodeFunctions[i.opCode].function
+// readsMemory checks that the length bytes at address are in memory and lets watchpoints know they are read
+func (i Instruction) readsMemory(address uint16, length int) error {
+	if err := i.checkMemory(address, length); err != nil {
+		return err
+	}
+	i.vm.recordAccess(false, address, length)
+	return nil
 }
 
-func (i *Instruction) getOpcodeName() string {
-	opCodeFunctions := i.primaryOpcodes()
-	return opCodeFunctions[i.opCode].name
+// writesMemory checks that the length bytes at address are in memory and lets watchpoints know they are written
+func (i Instruction) writesMemory(address uint16, length int) error {
+	if err := i.checkMemory(address, length); err != nil {
+		return err
+	}
+	i.vm.recordAccess(true, address, length)
+	return nil
 }
 
-func (i *Instruction) opDisplay() {
+func (i Instruction) opDisplay() error {
 	heightInPixels := i.opCode2
+	screen := i.vm.displayBuffer
 
-	i.vm.xCoord = i.vm.registers[i.vx] & 63
-	i.vm.yCoord = i.vm.registers[i.vy] & 31
+	i.vm.xCoord = i.vm.registers[i.vx] & byte(screen.Width()-1)
+	i.vm.yCoord = i.vm.registers[i.vy] & byte(screen.Height()-1)
 	i.vm.registers[15] = 0
 
-	fmt.Printf("Draw index %X, xreg: %d, yreg: %d, x: %d, y: %d, numBytes: %d\n", i.vm.indexRegister, i.vx, i.vy, i.vm.xCoord, i.vm.yCoord, heightInPixels)
-	overflow := i.vm.display.DrawSprite(i.vm.indexRegister, heightInPixels, i.vm.xCoord, i.vm.yCoord, i.vm.Memory)
+	// DXY0 draws a 16x16 sprite, as on the SUPER-CHIP
+	spriteBytes := int(heightInPixels)
+	if heightInPixels == 0 {
+		spriteBytes = 32
+	}
+	// Each selected XO-CHIP plane has its own sprite data, one after the other
+	spriteBytes *= screen.SelectedPlaneCount()
+	if err := i.readsMemory(i.vm.indexRegister, spriteBytes); err != nil {
+		return err
+	}
+
+	var overflow bool
+	if heightInPixels == 0 {
+		overflow = screen.DrawLargeSprite(i.vm.indexRegister, i.vm.xCoord, i.vm.yCoord, i.vm.Memory[:])
+	} else {
+		overflow = screen.DrawSprite(i.vm.indexRegister, heightInPixels, i.vm.xCoord, i.vm.yCoord, i.vm.Memory[:])
+	}
 	if overflow == true {
 		i.vm.registers[0x0F] = 1
 	}
+	if i.vm.quirks.DisplayWait {
+		i.vm.waitForVBlank()
+	}
+	return nil
 }
 
-func (i *Instruction) opRandom() {
+func (i Instruction) opRandom() error {
 	randomNumber := i.vm.random.Generate()
 	i.vm.registers[i.vx] = randomNumber & i.secondByte
+	return nil
 }
 
-func (i *Instruction) jumpWithOffset() {
-	i.vm.pc = uint16(i.vm.registers[0]) + i.address
-	i.vm.pcIncrementer = 0
-	fmt.Printf("Jump with offset to %X\n", i.vm.pc)
+func (i Instruction) jumpWithOffset() error {
+	offsetRegister := byte(0)
+	if i.vm.quirks.JumpUsesVX {
+		offsetRegister = i.vx
+	}
+	i.vm.pc = uint16(i.vm.registers[offsetRegister]) + i.address
+	return nil
 }
 
-func (i *Instruction) setIndexRegister() {
+// skip moves past the next instruction, which is 4 bytes long if it is the XO-CHIP long index load
+func (i Instruction) skip() {
+	if int(i.vm.pc)+1 < len(i.vm.Memory) && bytesToWord(i.vm.Memory[i.vm.pc], i.vm.Memory[i.vm.pc+1]) == 0xF000 {
+		i.vm.pc += 2
+	}
+	i.vm.pc += 2
+}
+
+func (i Instruction) setIndexRegister() error {
 	i.vm.indexRegister = i.address
+	return nil
 }
 
-func (i *Instruction) skipIfRegistersNotEqual() {
+func (i Instruction) skipIfRegistersNotEqual() error {
 	if i.vm.registers[i.vx] != i.vm.registers[i.vy] {
-		i.vm.pc += 2
+		i.skip()
 	}
+	return nil
 }
 
-func (i *Instruction) setRegister() {
-	//fmt.Printf("SetRegister %d to %d\n", index, secondByte)
+func (i Instruction) setRegister() error {
 	i.vm.registers[i.vx] = i.secondByte
+	return nil
 }
 
-func (i *Instruction) addToRegister() {
-	fmt.Printf("Add To Register [%d] value %d\n", i.vx, i.secondByte)
+func (i Instruction) addToRegister() error {
 	i.vm.registers[i.vx] += i.secondByte
-	fmt.Printf("&&&&&& VX = %x\n", i.vm.registers[i.vx])
+	return nil
 }
 
-func (i *Instruction) skipIfRegistersEqual() {
+func (i Instruction) longSetIndexRegister() error {
+	if err := i.checkMemory(i.vm.pc, 2); err != nil {
+		return err
+	}
+	i.vm.indexRegister = bytesToWord(i.vm.Memory[i.vm.pc], i.vm.Memory[i.vm.pc+1])
+	i.vm.pc += 2
+	return nil
+}
+
+func (i Instruction) selectPlanes() error {
+	i.vm.displayBuffer.SelectPlanes(i.vx)
+	return nil
+}
+
+// saveRange stores VX to VY in memory starting at I, in reverse order if X is greater than Y
+func (i Instruction) saveRange() error {
+	count, step := i.registerRange()
+	if err := i.writesMemory(i.vm.indexRegister, count); err != nil {
+		return err
+	}
+	register := int(i.vx)
+	for n := 0; n < count; n++ {
+		i.vm.Memory[int(i.vm.indexRegister)+n] = i.vm.registers[register]
+		register += step
+	}
+	return nil
+}
+
+// loadRange loads VX to VY from memory starting at I, in reverse order if X is greater than Y
+func (i Instruction) loadRange() error {
+	count, step := i.registerRange()
+	if err := i.readsMemory(i.vm.indexRegister, count); err != nil {
+		return err
+	}
+	register := int(i.vx)
+	for n := 0; n < count; n++ {
+		i.vm.registers[register] = i.vm.Memory[int(i.vm.indexRegister)+n]
+		register += step
+	}
+	return nil
+}
+
+func (i Instruction) registerRange() (count int, step int) {
+	if i.vx > i.vy {
+		return int(i.vx-i.vy) + 1, -1
+	}
+	return int(i.vy-i.vx) + 1, 1
+}
+
+func (i Instruction) skipIfRegistersEqual() error {
 	if i.vm.registers[i.vx] == i.vm.registers[i.vy] {
-		i.vm.pc += 2
+		i.skip()
 	}
+	return nil
 }
 
-func (i *Instruction) skipIfNotEqual() {
+func (i Instruction) skipIfNotEqual() error {
 	if i.vm.registers[i.vx] != i.secondByte {
-		i.vm.pc += 2
+		i.skip()
 	}
+	return nil
 }
 
-func (i *Instruction) skipIfEqual() {
+func (i Instruction) skipIfEqual() error {
 	if i.vm.registers[i.vx] == i.secondByte {
-		i.vm.pc += 2
+		i.skip()
 	}
+	return nil
 }
 
-func (i *Instruction) subroutine() {
-	i.vm.theStack.Push(i.vm.pc)
+func (i Instruction) subroutine() error {
+	if err := i.vm.theStack.Push(i.vm.pc); err != nil {
+		return i.fault(StackOverflow)
+	}
 	i.vm.pc = i.address
-	//i.vm.pcIncrementer = 0
+	return nil
 }
 
-// TODO: Need some more tests around jump, as commenting out the pcIncrementer line causes a ROM to work
-// AND the Trip8 demo also works properly WITHOUT it.
-func (i *Instruction) jump() {
+func (i Instruction) jump() error {
 	i.vm.pc = i.address
-	//i.vm.pcIncrementer = 0
+	return nil
 }
 
-func (i *Instruction) opReturn() {
-	address, _ := i.vm.theStack.Pop()
+func (i Instruction) opReturn() error {
+	address, err := i.vm.theStack.Pop()
+	if err != nil {
+		return i.fault(StackUnderflow)
+	}
 	i.vm.pc = address
-	fmt.Printf("Stack popped %X\n", i.vm.pc)
+	return nil
+}
+
+func (i Instruction) clearScreen() error {
+	i.vm.displayBuffer.ClearScreen()
+	return nil
+}
+
+func (i Instruction) scrollDown() error {
+	i.vm.displayBuffer.ScrollDown(int(i.opCode2))
+	return nil
 }
 
-func (i *Instruction) clearScreen() {
-	println("ClearScreen")
-	i.vm.display.ClearScreen()
+func (i Instruction) scrollRight() error {
+	i.vm.displayBuffer.ScrollRight(4)
+	return nil
 }
 
-func (i *Instruction) executeArithmeticInstructions() {
-	opCodeFunctions := i.arithmeticOpcodes()
+func (i Instruction) scrollLeft() error {
+	i.vm.displayBuffer.ScrollLeft(4)
+	return nil
+}
+
+func (i Instruction) exit() error {
+	return errExit
+}
 
-	fmt.Printf(">>> %s vx=%d vy=%d\n", opCodeFunctions[i.opCode2].name, i.vx, i.vy)
+func (i Instruction) lowResolution() error {
+	i.vm.displayBuffer.SetHighResolution(false)
+	return nil
+}
 
-	opCodeFunctions[i.opCode2].function()
+func (i Instruction) highResolution() error {
+	i.vm.displayBuffer.SetHighResolution(true)
+	return nil
 }
 
-func (i *Instruction) setVxToVy() {
+func (i Instruction) setVxToVy() error {
 	i.vm.registers[i.vx] = i.vm.registers[i.vy]
+	return nil
 }
 
-func (i *Instruction) or() {
+func (i Instruction) or() error {
 	i.vm.registers[i.vx] = i.vm.registers[i.vx] | i.vm.registers[i.vy]
+	i.resetFlagForLogic()
+	return nil
 }
 
-func (i *Instruction) and() {
+func (i Instruction) and() error {
 	i.vm.registers[i.vx] = i.vm.registers[i.vx] & i.vm.registers[i.vy]
+	i.resetFlagForLogic()
+	return nil
 }
 
-func (i *Instruction) xOr() {
+func (i Instruction) xOr() error {
 	i.vm.registers[i.vx] = i.vm.registers[i.vx] ^ i.vm.registers[i.vy]
+	i.resetFlagForLogic()
+	return nil
+}
+
+func (i Instruction) resetFlagForLogic() {
+	if i.vm.quirks.LogicResetsVF {
+		i.vm.registers[15] = 0
+	}
 }
 
-func (i *Instruction) addToVx() {
+func (i Instruction) addToVx() error {
 	vxRegister := i.vm.registers[i.vx]
 	vyRegister := i.vm.registers[i.vy]
 
 	i.vm.registers[i.vx] = vxRegister + vyRegister
 
-	fmt.Printf("******** vx = %d\n", i.vm.registers[i.vx])
-
 	var sum = uint16(vxRegister) + uint16(vyRegister)
 	if sum > 255 {
 		i.vm.registers[15] = 1
 	} else {
 		i.vm.registers[15] = 0
 	}
+	return nil
 }
 
-func (i *Instruction) subtractFromVx() {
+func (i Instruction) subtractFromVx() error {
 	vxRegister := i.vm.registers[i.vx]
 	vyRegister := i.vm.registers[i.vy]
 	i.vm.registers[i.vx] = vxRegister - vyRegister
-	fmt.Printf("******** vx = %d\n", i.vm.registers[i.vx])
 	var underflowFlag byte = 1
 	if vxRegister < vyRegister {
 		underflowFlag = 0
 	}
 	i.vm.registers[15] = underflowFlag
+	return nil
 }
 
-func (i *Instruction) shiftRight() {
-	overflow := i.vm.registers[i.vy] & 0b00000001
-	i.vm.registers[15] = overflow
-	i.vm.registers[i.vx] = i.vm.registers[i.vy] >> 1
+func (i Instruction) shiftRight() error {
+	value := i.shiftSource()
+	i.vm.registers[i.vx] = value >> 1
+	i.vm.registers[15] = value & 0b00000001
+	return nil
 }
 
-func (i *Instruction) shiftRightNew() {
-	i.vm.registers[i.vx] = i.vm.registers[i.vy]
-
-	overflow := i.vm.registers[i.vx] & 0b00000001
-	i.vm.registers[15] = overflow
-	i.vm.registers[i.vx] = i.vm.registers[i.vx] >> 1
-}
-
-func (i *Instruction) subtractFromVy() {
+func (i Instruction) subtractFromVy() error {
 	vxRegister := i.vm.registers[i.vx]
 	vyRegister := i.vm.registers[i.vy]
 	i.vm.registers[i.vx] = vyRegister - vxRegister
@@ -270,170 +346,151 @@ func (i *Instruction) subtractFromVy() {
 		underflowFlag = 0
 	}
 	i.vm.registers[15] = underflowFlag
+	return nil
 }
 
-func (i *Instruction) shiftLeft() {
-	overflow := i.vm.registers[i.vy] & 0b10000000
-	i.vm.registers[15] = overflow >> 7
-	i.vm.registers[i.vx] = i.vm.registers[i.vy] << 1
+func (i Instruction) shiftLeft() error {
+	value := i.shiftSource()
+	i.vm.registers[i.vx] = value << 1
+	i.vm.registers[15] = (value & 0b10000000) >> 7
+	return nil
 }
 
-func (i *Instruction) shiftLeftNew() {
-	i.vm.registers[i.vx] = i.vm.registers[i.vy]
-
-	overflow := i.vm.registers[i.vy] & 0b10000000
-	i.vm.registers[15] = overflow >> 7
-	i.vm.registers[i.vx] = i.vm.registers[i.vx] << 1
-}
-
-func (i *Instruction) furtherOperations() {
-	opcodes := i.furtherOpcodes()
-
-	functionName := opcodes[i.secondByte].name
-	fmt.Printf(">>> %s %x\n", functionName, i.secondByte)
-
-	f := opcodes[i.secondByte].function
-	f()
+func (i Instruction) shiftSource() byte {
+	if i.vm.quirks.ShiftUsesVY {
+		return i.vm.registers[i.vy]
+	}
+	return i.vm.registers[i.vx]
 }
 
-func (i *Instruction) bcd() {
+func (i Instruction) bcd() error {
 	value := i.vm.registers[i.vx]
 	hundreds, tens, ones := splitNumberIntoUnits(value)
 
 	address := i.vm.indexRegister
+	if err := i.writesMemory(address, 3); err != nil {
+		return err
+	}
 	i.vm.Memory[address] = hundreds
 	i.vm.Memory[address+1] = tens
 	i.vm.Memory[address+2] = ones
 
-	fmt.Printf("%d %d %d", hundreds, tens, ones)
+	return nil
+}
+
+func (i Instruction) fontChar() error {
+	character := i.vm.registers[i.vx]
+	i.vm.indexRegister = fontMemory + uint16(character)*5
+	return nil
 }
 
-func (i *Instruction) fontChar() {
-	println("*** i.vx = ", i.vx)
+func (i Instruction) largeFontChar() error {
 	character := i.vm.registers[i.vx]
-	println("** character = ", character)
-	i.vm.indexRegister = 0x50 + uint16(character)*5
-	fmt.Printf("** index = %x", i.vm.indexRegister)
+	i.vm.indexRegister = largeFontMemory + uint16(character&0x0F)*10
+	return nil
 }
 
-func (i *Instruction) getKey() {
-	// If we get a key then suspend processing of further instruction
-	i.vm.processInstructions = false
-	key := i.vm.display.GetKey()
-	println("****** getKey = ", key)
-	i.vm.registers[i.vx] = byte(key)
+func (i Instruction) getKey() error {
+	// As on the VIP, wait for a key to be pressed and released, only counting releases from now on
+	i.vm.keypad.clearReleased()
+	i.vm.waitingForKey = true
+	i.vm.keyRegister = i.vx
+	return nil
 }
 
-func (i *Instruction) addToIndex() {
+func (i Instruction) addToIndex() error {
 	i.vm.indexRegister += uint16(i.vm.registers[i.vx])
+	return nil
 }
 
-func (i *Instruction) store() {
+func (i Instruction) store() error {
 	max := int(i.vx)
 	startMemory := i.vm.indexRegister
+	if err := i.writesMemory(startMemory, max+1); err != nil {
+		return err
+	}
 	for n := 0; n <= max; n++ {
 		i.vm.Memory[startMemory] = i.vm.registers[n]
 		startMemory++
 	}
+	i.incrementIndexAfterLoadStore()
+	return nil
 }
 
-func (i *Instruction) load() {
+func (i Instruction) load() error {
 	startMemory := i.vm.indexRegister
+	if err := i.readsMemory(startMemory, int(i.vx)+1); err != nil {
+		return err
+	}
 	for n := 0; n <= int(i.vx); n++ {
 		i.vm.registers[n] = i.vm.Memory[startMemory]
 		startMemory++
 	}
+	i.incrementIndexAfterLoadStore()
+	return nil
+}
+
+func (i Instruction) incrementIndexAfterLoadStore() {
+	if i.vm.quirks.LoadStoreIncrementsIndex {
+		i.vm.indexRegister += uint16(i.vx) + 1
+	}
+}
+
+func (i Instruction) storeFlags() error {
+	copy(i.vm.rplFlags[:i.vx+1], i.vm.registers[:i.vx+1])
+	return nil
 }
 
-func (i *Instruction) getDelayTimer() {
-	// TODO: Test
+func (i Instruction) loadFlags() error {
+	copy(i.vm.registers[:i.vx+1], i.vm.rplFlags[:i.vx+1])
+	return nil
+}
+
+func (i Instruction) getDelayTimer() error {
 	// FX07 sets VX to value of the delay timer
-	i.vm.registers[i.vx] = i.vm.delayTimer.timer
+	i.vm.registers[i.vx] = i.vm.timers.Delay()
+	return nil
 }
 
-func (i *Instruction) setDelayTimer() {
-	// TODO: Test
+func (i Instruction) setDelayTimer() error {
 	// FX15 set the delay timer to value in VX
-	i.vm.setDelayTimer(i.vm.registers[i.vx])
+	i.vm.timers.setDelay(i.vm.registers[i.vx])
+	return nil
 }
 
-func (i *Instruction) setSoundTimer() {
-	// TODO: Test
+func (i Instruction) setSoundTimer() error {
 	// FX18 sets sound timer to value in VX
-	println("vx = ", i.vx)
+	i.vm.timers.setSound(i.vm.registers[i.vx])
+	i.vm.updateTone()
+	return nil
 }
 
-func (i *Instruction) skipIfKey() {
-
-	if i.secondByte == 0x9E {
-		key := i.vm.display.GetKey()
-		println("****** skipIfKey = ", key)
-
-		if byte(key) == i.vm.registers[i.vx] {
-			i.vm.pc += 2
-		}
+// loadAudioPattern copies the 16 byte XO-CHIP sound pattern from memory at I
+func (i Instruction) loadAudioPattern() error {
+	if err := i.readsMemory(i.vm.indexRegister, len(i.vm.soundPattern.Buffer)); err != nil {
+		return err
+	}
+	copy(i.vm.soundPattern.Buffer[:], i.vm.Memory[i.vm.indexRegister:])
+	i.vm.audio.SetPattern(i.vm.soundPattern)
+	return nil
+}
 
-	} else if i.secondByte == 0xA1 {
-		key := i.vm.display.GetKey()
-		println("****** skipIfNotKey = ", key)
+func (i Instruction) setPitch() error {
+	i.vm.soundPattern.Pitch = i.vm.registers[i.vx]
+	i.vm.audio.SetPattern(i.vm.soundPattern)
+	return nil
+}
 
-		if byte(key) != i.vm.registers[i.vx] {
-			i.vm.pc += 2
-		}
+func (i Instruction) skipIfKeyPressed() error {
+	if i.vm.keypad.IsPressed(i.vm.registers[i.vx]) {
+		i.skip()
 	}
-
+	return nil
 }
 
-func (i *Instruction) primaryOpcodes() map[byte]Opcode {
-	return map[byte]Opcode{
-		ClearScreen:             {name: "ClearScreen", function: i.clearScreen},
-		Return:                  {name: "Return", function: i.opReturn},
-		Jump:                    {name: "Jump", function: i.jump},
-		Subroutine:              {name: "Subroutine", function: i.subroutine},
-		SkipIfEqual:             {name: "SkipIfEqual", function: i.skipIfEqual},
-		SkipIfNotEqual:          {name: "SkipIfNotEqual", function: i.skipIfNotEqual},
-		SkipIfRegistersEqual:    {name: "SkipIfRegistersEqual", function: i.skipIfRegistersEqual},
-		SkipIfRegistersNotEqual: {name: "SkipIfRegistersNotEqual", function: i.skipIfRegistersNotEqual},
-		SetRegister:             {name: "SetRegister", function: i.setRegister},
-		AddToRegister:           {name: "AddToRegister", function: i.addToRegister},
-		SetIndexRegister:        {name: "SetIndexRegister", function: i.setIndexRegister},
-		JumpWithOffset:          {name: "JumpWithOffset", function: i.jumpWithOffset},
-		OpRandom:                {name: "OpRandom", function: i.opRandom},
-		Display:                 {name: "Display", function: i.opDisplay},
-		SkipIfKey:               {name: "SkipIfKey", function: i.skipIfKey},
-		BitwiseOperations:       {name: "BitwiseOperations", function: i.executeArithmeticInstructions},
-		FurtherOperations:       {function: i.furtherOperations},
-	}
-}
-
-func (i *Instruction) arithmeticOpcodes() map[byte]Opcode {
-	return map[byte]Opcode{
-		setVxToVy:      {name: "SetVxToBy", function: i.setVxToVy},
-		binaryOr:       {name: "Or", function: i.or},
-		binaryAnd:      {name: "And", function: i.and},
-		logicalXor:     {name: "Xor", function: i.xOr},
-		addToVx:        {name: "AddToVx", function: i.addToVx},
-		subtractFromVx: {name: "SubtractFromVx", function: i.subtractFromVx},
-		shiftRight:     {name: "ShiftRight", function: i.shiftRight},
-		subtractFromVy: {name: "SubtractFromVy", function: i.subtractFromVy},
-		shiftLeft:      {name: "ShiftLeft", function: i.shiftLeft},
-	}
-}
-
-func (i *Instruction) furtherOpcodes() map[byte]Opcode {
-	return map[byte]Opcode{
-		Bcd:           {name: "Bcd", function: i.bcd},
-		FontChar:      {name: "FontChar", function: i.fontChar},
-		GetKey:        {name: "GetKey", function: i.getKey},
-		AddToIndex:    {name: "AddToIndex", function: i.addToIndex},
-		Store:         {name: "Store", function: i.store},
-		Load:          {name: "Load", function: i.load},
-		GetDelayTimer: {name: "GetDelayTimer", function: i.getDelayTimer},
-		SetDelayTimer: {name: "SetDelayTimer", function: i.setDelayTimer},
-		SetSoundTimer: {name: "SetSoundTimer", function: i.setSoundTimer},
-	}
-}
-
-func (i *Instruction) print() {
-	fmt.Printf("Instuction: %x, Name: %s, Opcode %x\n", i.instr, i.getOpcodeName(), i.opCode)
+func (i Instruction) skipIfKeyNotPressed() error {
+	if !i.vm.keypad.IsPressed(i.vm.registers[i.vx]) {
+		i.skip()
+	}
+	return nil
 }
@@ -1,26 +1,89 @@
 package chip8
 
-func KeyCodeToValue(i int) byte {
-	keys := map[int]byte{
-		49: 0x1,
-		50: 0x2,
-		51: 0x3,
-		52: 0xC,
-
-		113: 0x4,
-		119: 0x5,
-		101: 0x6,
-		114: 0xD,
-
-		97:  0x7,
-		115: 0x8,
-		100: 0x9,
-		102: 0xE,
-
-		122: 0xA,
-		120: 0x0,
-		99:  0xB,
-		118: 0xF,
+import "math/bits"
+
+// Keypad holds which of the 16 keys are held down, and which have been released since the last frame.
+type Keypad struct {
+	pressed  uint16
+	released uint16
+}
+
+// Press marks key as held down.
+func (k *Keypad) Press(key byte) {
+	k.pressed |= keyBit(key)
+}
+
+// Release marks key as no longer held down, remembering it was released if it was held.
+func (k *Keypad) Release(key byte) {
+	bit := keyBit(key)
+	if k.pressed&bit != 0 {
+		k.released |= bit
 	}
-	return keys[i]
+	k.pressed &^= bit
+}
+
+func (k *Keypad) IsPressed(key byte) bool {
+	return k.pressed&keyBit(key) != 0
+}
+
+// Pressed returns a bit for each key held down, key 0 in the lowest bit.
+func (k *Keypad) Pressed() uint16 {
+	return k.pressed
+}
+
+// Released returns a bit for each key released since the last frame.
+func (k *Keypad) Released() uint16 {
+	return k.released
+}
+
+// Set replaces the keys held down and released, as when replaying a movie.
+func (k *Keypad) Set(pressed uint16, released uint16) {
+	k.pressed = pressed
+	k.released = released
+}
+
+func (k *Keypad) clearReleased() {
+	k.released = 0
+}
+
+// takeReleased returns the lowest key released since the last frame, and forgets that it was released.
+func (k *Keypad) takeReleased() (byte, bool) {
+	if k.released == 0 {
+		return 0, false
+	}
+	key := byte(bits.TrailingZeros16(k.released))
+	k.released &^= keyBit(key)
+	return key, true
+}
+
+func keyBit(key byte) uint16 {
+	return 1 << (key & 0xF)
+}
+
+var keyCodes = map[int]byte{
+	49: 0x1,
+	50: 0x2,
+	51: 0x3,
+	52: 0xC,
+
+	113: 0x4,
+	119: 0x5,
+	101: 0x6,
+	114: 0xD,
+
+	97:  0x7,
+	115: 0x8,
+	100: 0x9,
+	102: 0xE,
+
+	122: 0xA,
+	120: 0x0,
+	99:  0xB,
+	118: 0xF,
+}
+
+// KeyCodeToValue returns the keypad key for a key on the keyboard, laid out as 1234/QWER/ASDF/ZXCV.
+func KeyCodeToValue(i int) (byte, bool) {
+	key, ok := keyCodes[i]
+	return key, ok
 }
@@ -10,9 +10,56 @@ type KeyPadTestSuite struct {
 }
 
 func (suite *KeyPadTestSuite) TestKeyCodesAreMappedToCorrectValues() {
-	suite.Equal(byte(0x1), KeyCodeToValue(49))
-	suite.Equal(byte(0x2), KeyCodeToValue(50))
-	suite.Equal(byte(0x3), KeyCodeToValue(51))
+	for code, expected := range map[int]byte{49: 0x1, 50: 0x2, 51: 0x3, 120: 0x0} {
+		key, ok := KeyCodeToValue(code)
+		suite.True(ok)
+		suite.Equal(expected, key)
+	}
+}
+
+func (suite *KeyPadTestSuite) TestUnmappedKeyCodes() {
+	_, ok := KeyCodeToValue(32)
+	suite.False(ok)
+}
+
+func (suite *KeyPadTestSuite) TestSeveralKeysCanBeHeld() {
+	var keypad Keypad
+	keypad.Press(0x1)
+	keypad.Press(0xF)
+
+	suite.True(keypad.IsPressed(0x1))
+	suite.True(keypad.IsPressed(0xF))
+	suite.False(keypad.IsPressed(0x2))
+	suite.Equal(uint16(0x8002), keypad.Pressed())
+
+	keypad.Release(0x1)
+
+	suite.False(keypad.IsPressed(0x1))
+	suite.True(keypad.IsPressed(0xF))
+}
+
+func (suite *KeyPadTestSuite) TestOnlyHeldKeysAreReleased() {
+	var keypad Keypad
+	keypad.Release(0x3)
+	suite.Equal(uint16(0), keypad.Released())
+
+	keypad.Press(0x3)
+	keypad.Release(0x3)
+	suite.Equal(uint16(0x0008), keypad.Released())
+}
+
+func (suite *KeyPadTestSuite) TestReleasedKeysAreTakenLowestFirst() {
+	var keypad Keypad
+	keypad.Set(0, 0x0120)
+
+	key, ok := keypad.takeReleased()
+	suite.True(ok)
+	suite.Equal(byte(0x5), key)
+	key, ok = keypad.takeReleased()
+	suite.True(ok)
+	suite.Equal(byte(0x8), key)
+	_, ok = keypad.takeReleased()
+	suite.False(ok)
 }
 
 func TestKeyPadTestSuite(t *testing.T) {
@@ -0,0 +1,19 @@
+package chip8
+
+type mockAudio struct {
+	tones    []bool
+	patterns []SoundPattern
+	frames   int
+}
+
+func (m *mockAudio) SetTone(on bool) {
+	m.tones = append(m.tones, on)
+}
+
+func (m *mockAudio) SetPattern(pattern SoundPattern) {
+	m.patterns = append(m.patterns, pattern)
+}
+
+func (m *mockAudio) EndFrame() {
+	m.frames++
+}
@@ -1,40 +1,28 @@
 package chip8
 
 type mockDisplay struct {
-	screenCleared bool
-	values        drawPatternValues
-	eventType     EventType
-	fakeKey       int
+	renders   int
+	eventType EventType
+	keys      uint16
 }
 
-func (k *mockDisplay) GetKey() int {
-	return k.fakeKey
+// setKeys sets the keys held down when the events are next polled.
+func (m *mockDisplay) setKeys(keys uint16) {
+	m.keys = keys
 }
 
-func (k *mockDisplay) SetKey(key int) {
-	k.fakeKey = key
+func (m *mockDisplay) Render(buffer *DisplayBuffer) {
+	m.renders++
 }
 
-type drawPatternValues struct {
-	x             byte
-	y             byte
-	address       uint16
-	numberOfBytes byte
-}
-
-func (m *mockDisplay) DrawSprite(address uint16, numberOfBytes byte, x byte, y byte, memory [4096]byte) bool {
-	values := drawPatternValues{
-		x, y, address, numberOfBytes,
+func (m *mockDisplay) PollEvents(keypad *Keypad) EventType {
+	for key := byte(0); key < 16; key++ {
+		if m.keys&keyBit(key) != 0 {
+			keypad.Press(key)
+		} else {
+			keypad.Release(key)
+		}
 	}
-	m.values = values
-	return true
-}
-
-func (m *mockDisplay) ClearScreen() {
-	m.screenCleared = true
-}
-
-func (m *mockDisplay) PollEvents() EventType {
 	return m.eventType
 }
 
@@ -0,0 +1,9 @@
+package chip8
+
+type mockTracer struct {
+	events []TraceEvent
+}
+
+func (m *mockTracer) Trace(event TraceEvent) {
+	m.events = append(m.events, event)
+}
@@ -0,0 +1,186 @@
+package chip8
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// A movie starts with the magic number, the version of the format and movieHeader, then the index of
+// the random generator in RandomNames, followed by a MovieFrame for each frame, little endian.
+const movieMagic = "C8MV"
+const movieVersion = 1
+
+// Movie is the input to a run of a program, along with everything else that decides what the program
+// does, so that the run can be replayed exactly.
+type Movie struct {
+	ROMHash [sha256.Size]byte
+	// Random is the name of the random generator, as given to RandomByName.
+	Random               string
+	Seed                 int64
+	Quirks               Quirks
+	InstructionsPerFrame int
+	Frames               []MovieFrame
+}
+
+// MovieFrame is the input seen at the end of a frame: the keys held down and the keys released, as
+// returned by Keypad.Pressed and Keypad.Released.
+type MovieFrame struct {
+	Pressed  uint16
+	Released uint16
+}
+
+type movieHeader struct {
+	Magic                [4]byte
+	Version              uint16
+	ROMHash              [sha256.Size]byte
+	Seed                 int64
+	Quirks               uint16
+	InstructionsPerFrame uint16
+	FrameCount           uint32
+}
+
+// Check returns an error unless vm has loaded the ROM the movie was recorded with.
+func (m *Movie) Check(vm *VM) error {
+	if vm.ROMHash() != m.ROMHash {
+		return errors.New("movie was recorded with a different ROM")
+	}
+	return nil
+}
+
+func (m *Movie) WriteTo(w io.Writer) (int64, error) {
+	random := -1
+	for n, name := range RandomNames {
+		if name == m.Random {
+			random = n
+		}
+	}
+	if random < 0 {
+		return 0, fmt.Errorf("unknown random generator %q", m.Random)
+	}
+	header := movieHeader{
+		Version:              movieVersion,
+		ROMHash:              m.ROMHash,
+		Seed:                 m.Seed,
+		Quirks:               m.Quirks.bits(),
+		InstructionsPerFrame: uint16(m.InstructionsPerFrame),
+		FrameCount:           uint32(len(m.Frames)),
+	}
+	copy(header.Magic[:], movieMagic)
+
+	out := bufio.NewWriter(w)
+	for _, data := range []interface{}{header, byte(random), m.Frames} {
+		if err := binary.Write(out, binary.LittleEndian, data); err != nil {
+			return 0, err
+		}
+	}
+	return int64(binary.Size(header) + 1 + binary.Size(m.Frames)), out.Flush()
+}
+
+func ReadMovie(r io.Reader) (*Movie, error) {
+	var header movieHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("reading movie: %w", err)
+	}
+	if string(header.Magic[:]) != movieMagic {
+		return nil, errors.New("not a movie")
+	}
+	if header.Version != movieVersion {
+		return nil, fmt.Errorf("unsupported movie version %d", header.Version)
+	}
+	var random byte
+	if err := binary.Read(r, binary.LittleEndian, &random); err != nil {
+		return nil, fmt.Errorf("reading movie: %w", err)
+	}
+	if int(random) >= len(RandomNames) {
+		return nil, fmt.Errorf("unknown random generator %d in movie", random)
+	}
+	m := &Movie{
+		ROMHash:              header.ROMHash,
+		Random:               RandomNames[random],
+		Seed:                 header.Seed,
+		Quirks:               quirksFromBits(header.Quirks),
+		InstructionsPerFrame: int(header.InstructionsPerFrame),
+		Frames:               make([]MovieFrame, header.FrameCount),
+	}
+	if err := binary.Read(r, binary.LittleEndian, m.Frames); err != nil {
+		return nil, fmt.Errorf("reading movie: %w", err)
+	}
+	return m, nil
+}
+
+// MovieRecorder is a display that records the input from another display once a frame.
+type MovieRecorder struct {
+	display DisplayInterface
+	frames  []MovieFrame
+}
+
+func NewMovieRecorder(display DisplayInterface) *MovieRecorder {
+	return &MovieRecorder{display: display}
+}
+
+// Movie returns the frames recorded so far as a movie of vm, which is using the random generator
+// called random, started from seed.
+func (r *MovieRecorder) Movie(vm *VM, random string, seed int64) *Movie {
+	return &Movie{
+		ROMHash:              vm.ROMHash(),
+		Random:               random,
+		Seed:                 seed,
+		Quirks:               vm.quirks,
+		InstructionsPerFrame: vm.instructionsPerFrame,
+		Frames:               append([]MovieFrame(nil), r.frames...),
+	}
+}
+
+func (r *MovieRecorder) Render(buffer *DisplayBuffer) {
+	r.display.Render(buffer)
+}
+
+func (r *MovieRecorder) PollEvents(keypad *Keypad) EventType {
+	event := r.display.PollEvents(keypad)
+	if event == QuitEvent {
+		return event
+	}
+	r.frames = append(r.frames, MovieFrame{Pressed: keypad.Pressed(), Released: keypad.Released()})
+	return event
+}
+
+// MoviePlayer is a display that gives the input from a movie rather than the keyboard. It shows the
+// frames on another display, which can still be closed, and quits when the movie ends.
+type MoviePlayer struct {
+	display DisplayInterface
+	movie   *Movie
+	frame   int
+	// keyboard takes the keys pressed on the other display, which are ignored
+	keyboard Keypad
+}
+
+func NewMoviePlayer(display DisplayInterface, movie *Movie) *MoviePlayer {
+	return &MoviePlayer{display: display, movie: movie}
+}
+
+func (p *MoviePlayer) Render(buffer *DisplayBuffer) {
+	p.display.Render(buffer)
+}
+
+func (p *MoviePlayer) PollEvents(keypad *Keypad) EventType {
+	if p.display.PollEvents(&p.keyboard) == QuitEvent || p.Finished() {
+		return QuitEvent
+	}
+	frame := p.movie.Frames[p.frame]
+	p.frame++
+	var event EventType = NoEvent
+	if frame.Pressed != keypad.Pressed() || frame.Released != 0 {
+		event = KeyboardEvent
+	}
+	keypad.Set(frame.Pressed, frame.Released)
+	return event
+}
+
+// Finished reports whether every frame of the movie has been played.
+func (p *MoviePlayer) Finished() bool {
+	return p.frame >= len(p.movie.Frames)
+}
@@ -0,0 +1,128 @@
+package chip8
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type MovieTestSuite struct {
+	suite.Suite
+	code []byte
+}
+
+func (suite *MovieTestSuite) SetupTest() {
+	// Waits for a key, then draws random digits at the position of the key until the next one, and
+	// draws another digit while key 1 is held
+	a := NewAssembler()
+	a.Label("wait")
+	a.GetKey(0)
+	a.SetRegister(1, 8)
+	a.Label("draw")
+	a.Random(2, 0x0F)
+	a.FontChar(2)
+	a.Display(0, 1, 5)
+	a.SetRegister(3, 1)
+	a.SkipIfKeyNotPressed(3)
+	a.Display(1, 0, 5)
+	a.AddToRegister(1, 1)
+	a.SkipIfEqual(1, 20)
+	a.JumpTo("draw")
+	a.JumpTo("wait")
+	code, err := a.Assemble()
+	suite.Require().NoError(err)
+	suite.code = code
+}
+
+func (suite *MovieTestSuite) newVM(display DisplayInterface, seed int64) *VM {
+	vm := NewVM(display, NewSeededRandom(seed), QuirksCosmacVIP)
+	vm.SetClock(NewVirtualClock())
+	vm.Load(suite.code)
+	return vm
+}
+
+// runFrames runs until the display quits, returning a copy of the pixels after every frame.
+func (suite *MovieTestSuite) runFrames(vm *VM, frames int, input func(frame int)) [][][]byte {
+	var pixels [][][]byte
+	for frame := 0; frame < frames; frame++ {
+		_, err := vm.RunFrame()
+		suite.Require().NoError(err)
+		input(frame)
+		if !vm.PresentFrame() {
+			break
+		}
+		pixels = append(pixels, vm.State().Display.Pixels)
+	}
+	return pixels
+}
+
+func (suite *MovieTestSuite) record(frames int) (*Movie, [][][]byte) {
+	keyboard := &mockDisplay{eventType: NoEvent}
+	recorder := NewMovieRecorder(keyboard)
+	vm := suite.newVM(recorder, 1234)
+
+	pixels := suite.runFrames(vm, frames, func(frame int) {
+		keyboard.setKeys(0)
+		if frame%7 == 3 {
+			keyboard.setKeys(keyBit(byte(frame)) | 0x0001)
+		}
+	})
+	return recorder.Movie(vm, "pseudo", 1234), pixels
+}
+
+func (suite *MovieTestSuite) TestReplayGivesIdenticalFrames() {
+	movie, recorded := suite.record(60)
+	suite.Len(movie.Frames, 60)
+
+	player := NewMoviePlayer(&mockDisplay{eventType: NoEvent}, movie)
+	vm := suite.newVM(player, movie.Seed)
+	suite.Require().NoError(movie.Check(vm))
+	replayed := suite.runFrames(vm, 100, func(int) {})
+
+	suite.True(player.Finished())
+	suite.Equal(recorded, replayed)
+}
+
+func (suite *MovieTestSuite) TestDifferentSeedGivesDifferentFrames() {
+	movie, recorded := suite.record(60)
+
+	vm := suite.newVM(NewMoviePlayer(&mockDisplay{eventType: NoEvent}, movie), movie.Seed+1)
+	replayed := suite.runFrames(vm, 100, func(int) {})
+
+	suite.NotEqual(recorded, replayed)
+}
+
+func (suite *MovieTestSuite) TestWriteAndRead() {
+	movie, _ := suite.record(20)
+	movie.Random = "table"
+	movie.Quirks = QuirksXOChip
+	movie.InstructionsPerFrame = 1000
+
+	var file bytes.Buffer
+	n, err := movie.WriteTo(&file)
+	suite.Require().NoError(err)
+	suite.Equal(int64(file.Len()), n)
+	read, err := ReadMovie(&file)
+
+	suite.Require().NoError(err)
+	suite.Equal(movie, read)
+}
+
+func (suite *MovieTestSuite) TestBadMovies() {
+	_, err := ReadMovie(bytes.NewReader([]byte("XXXX0123456789012345678901234567890123456789012345678901234567890")))
+	suite.EqualError(err, "not a movie")
+
+	movie, _ := suite.record(5)
+	var file bytes.Buffer
+	movie.WriteTo(&file)
+	_, err = ReadMovie(bytes.NewReader(file.Bytes()[:file.Len()-1]))
+	suite.Error(err)
+
+	other := NewVM(&mockDisplay{}, MockRandom{}, QuirksCosmacVIP)
+	other.Load([]byte{0x00, 0xE0})
+	suite.EqualError(movie.Check(other), "movie was recorded with a different ROM")
+}
+
+func TestMovieSuite(t *testing.T) {
+	suite.Run(t, new(MovieTestSuite))
+}
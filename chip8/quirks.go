@@ -0,0 +1,71 @@
+package chip8
+
+import "strings"
+
+// Quirks selects how the ambiguous opcodes behave, as they differ between the
+// platforms that CHIP-8 programs were originally written for.
+type Quirks struct {
+	// ShiftUsesVY makes 8XY6/8XYE shift VY into VX, otherwise VX is shifted in place.
+	ShiftUsesVY bool
+	// LoadStoreIncrementsIndex makes FX55/FX65 leave I pointing past the last register.
+	LoadStoreIncrementsIndex bool
+	// JumpUsesVX makes BNNN behave as BXNN, adding VX rather than V0 to the address.
+	JumpUsesVX bool
+	// LogicResetsVF makes 8XY1/8XY2/8XY3 set VF to 0.
+	LogicResetsVF bool
+	// WrapSprites makes sprites wrap around the edges of the screen rather than being clipped.
+	WrapSprites bool
+	// DisplayWait makes DXYN wait for the vertical blank before execution continues.
+	DisplayWait bool
+	// ExtendedMemory gives the 64K address space of the XO-CHIP rather than 4K.
+	ExtendedMemory bool
+}
+
+var QuirksCosmacVIP = Quirks{
+	ShiftUsesVY:              true,
+	LoadStoreIncrementsIndex: true,
+	LogicResetsVF:            true,
+	DisplayWait:              true,
+}
+
+var QuirksChip48 = Quirks{
+	JumpUsesVX: true,
+}
+
+var QuirksSuperChip = Quirks{
+	JumpUsesVX: true,
+}
+
+var QuirksXOChip = Quirks{
+	ShiftUsesVY:              true,
+	LoadStoreIncrementsIndex: true,
+	WrapSprites:              true,
+	ExtendedMemory:           true,
+}
+
+type platform struct {
+	quirks               Quirks
+	instructionsPerFrame int
+}
+
+func platforms() map[string]platform {
+	return map[string]platform{
+		"vip":    {QuirksCosmacVIP, 11},
+		"chip8":  {QuirksCosmacVIP, 11},
+		"chip48": {QuirksChip48, 30},
+		"schip":  {QuirksSuperChip, 30},
+		"xochip": {QuirksXOChip, 1000},
+	}
+}
+
+// QuirksByName returns the preset for a platform name such as "vip", "chip48", "schip" or "xochip".
+func QuirksByName(name string) (Quirks, bool) {
+	p, ok := platforms()[strings.ToLower(name)]
+	return p.quirks, ok
+}
+
+// InstructionsPerFrameByName returns the usual speed of programs written for the named platform.
+func InstructionsPerFrameByName(name string) (int, bool) {
+	p, ok := platforms()[strings.ToLower(name)]
+	return p.instructionsPerFrame, ok
+}
@@ -1,7 +1,8 @@
 package chip8
 
 import (
-	"math/rand"
+	"encoding/binary"
+	"errors"
 	"time"
 )
 
@@ -9,16 +10,120 @@ type Random interface {
 	Generate() byte
 }
 
+// RandomNames lists the generators that RandomByName knows, the default first.
+var RandomNames = []string{"pseudo", "table"}
+
+// RandomByName returns the generator called name, started from seed.
+func RandomByName(name string, seed int64) (Random, bool) {
+	switch name {
+	case "pseudo":
+		return NewSeededRandom(seed), true
+	case "table":
+		return NewTableRandom(seed), true
+	}
+	return nil, false
+}
+
+// NewSeed returns a seed that is different every run.
+func NewSeed() int64 {
+	return time.Now().UnixNano()
+}
+
+// PseudoRandom generates the numbers for CXNN with SplitMix64, so the same Seed always gives the same
+// numbers and its state can be saved.
 type PseudoRandom struct {
-	Seed int64
+	Seed  int64
+	state uint64
 }
 
 func NewRandom() *PseudoRandom {
-	r := new(PseudoRandom)
-	r.Seed = time.Now().UnixNano()
+	return NewSeededRandom(NewSeed())
+}
+
+func NewSeededRandom(seed int64) *PseudoRandom {
+	return &PseudoRandom{Seed: seed, state: uint64(seed)}
+}
+
+func (pseudoRandom *PseudoRandom) Generate() byte {
+	pseudoRandom.state += 0x9E3779B97F4A7C15
+	z := pseudoRandom.state
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	z ^= z >> 31
+	return byte(z >> 56)
+}
+
+func (pseudoRandom *PseudoRandom) MarshalBinary() ([]byte, error) {
+	data := []byte{'P'}
+	data = appendUint64(data, uint64(pseudoRandom.Seed))
+	return appendUint64(data, pseudoRandom.state), nil
+}
+
+func (pseudoRandom *PseudoRandom) UnmarshalBinary(data []byte) error {
+	if len(data) != 17 || data[0] != 'P' {
+		return errDifferentRandom
+	}
+	pseudoRandom.Seed = int64(binary.LittleEndian.Uint64(data[1:]))
+	pseudoRandom.state = binary.LittleEndian.Uint64(data[9:])
+	return nil
+}
+
+// TableRandom adds a byte from a table, picked by a counter that goes up for every number, to the
+// last number. The table is filled from the seed. Its numbers repeat much sooner than PseudoRandom's,
+// which is handy for seeing how a program copes with poor random numbers.
+//
+// The COSMAC VIP works the same way, but its table is the interpreter's own code. Those bytes aren't
+// in this repository, so this is not the VIP generator and won't give the numbers a VIP would.
+type TableRandom struct {
+	Seed    int64
+	counter uint16
+	value   byte
+	table   [256]byte
+}
+
+func NewTableRandom(seed int64) *TableRandom {
+	r := &TableRandom{Seed: seed}
+	r.fillTable()
 	return r
 }
 
-func (pseudoRandom PseudoRandom) Generate() byte {
-	return byte(rand.Intn(256))
+func (r *TableRandom) fillTable() {
+	filler := NewSeededRandom(r.Seed)
+	for n := range r.table {
+		r.table[n] = filler.Generate()
+	}
+	r.counter = uint16(r.Seed)
+	r.value = byte(r.Seed >> 16)
+}
+
+func (r *TableRandom) Generate() byte {
+	r.counter++
+	r.value += r.table[byte(r.counter)]
+	return r.value
+}
+
+func (r *TableRandom) MarshalBinary() ([]byte, error) {
+	data := []byte{'T'}
+	data = appendUint64(data, uint64(r.Seed))
+	data = append(data, byte(r.counter), byte(r.counter>>8))
+	return append(data, r.value), nil
+}
+
+func (r *TableRandom) UnmarshalBinary(data []byte) error {
+	if len(data) != 12 || data[0] != 'T' {
+		return errDifferentRandom
+	}
+	r.Seed = int64(binary.LittleEndian.Uint64(data[1:]))
+	r.fillTable()
+	r.counter = binary.LittleEndian.Uint16(data[9:])
+	r.value = data[11]
+	return nil
+}
+
+var errDifferentRandom = errors.New("state is for a different random generator")
+
+func appendUint64(data []byte, value uint64) []byte {
+	var encoded [8]byte
+	binary.LittleEndian.PutUint64(encoded[:], value)
+	return append(data, encoded[:]...)
 }
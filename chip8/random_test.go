@@ -0,0 +1,75 @@
+package chip8
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type RandomTestSuite struct {
+	suite.Suite
+}
+
+func generate(random Random, count int) []byte {
+	numbers := make([]byte, count)
+	for n := range numbers {
+		numbers[n] = random.Generate()
+	}
+	return numbers
+}
+
+func (suite *RandomTestSuite) TestSameSeedGivesTheSameNumbers() {
+	for _, name := range RandomNames {
+		first, ok := RandomByName(name, 42)
+		suite.True(ok)
+		second, _ := RandomByName(name, 42)
+		other, _ := RandomByName(name, 43)
+
+		numbers := generate(first, 100)
+
+		suite.Equal(numbers, generate(second, 100), name)
+		suite.NotEqual(numbers, generate(other, 100), name)
+	}
+}
+
+func (suite *RandomTestSuite) TestGeneratorsDoNotShareState() {
+	first := NewSeededRandom(7)
+	expected := generate(NewSeededRandom(7), 10)
+
+	NewSeededRandom(7).Generate()
+	NewRandom().Generate()
+
+	suite.Equal(expected, generate(first, 10))
+}
+
+func (suite *RandomTestSuite) TestNumbersAreSpreadOut() {
+	seen := make(map[byte]bool)
+	for _, number := range generate(NewSeededRandom(1), 2000) {
+		seen[number] = true
+	}
+
+	suite.Greater(len(seen), 250)
+}
+
+func (suite *RandomTestSuite) TestStateCanBeSavedAndRestored() {
+	random := NewTableRandom(5)
+	generate(random, 300)
+	state, err := random.MarshalBinary()
+	suite.Require().NoError(err)
+	expected := generate(random, 10)
+
+	restored := NewTableRandom(0)
+	suite.Require().NoError(restored.UnmarshalBinary(state))
+
+	suite.Equal(expected, generate(restored, 10))
+	suite.Equal(errDifferentRandom, NewSeededRandom(0).UnmarshalBinary(state))
+}
+
+func (suite *RandomTestSuite) TestUnknownName() {
+	_, ok := RandomByName("dice", 1)
+
+	suite.False(ok)
+}
+
+func TestRandomSuite(t *testing.T) {
+	suite.Run(t, new(RandomTestSuite))
+}
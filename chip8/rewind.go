@@ -0,0 +1,156 @@
+package chip8
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// Rewind records a save state every frame so that a VM can be wound back in time. Only the newest
+// state is kept whole: each older frame is stored as the difference from the frame after it, and the
+// oldest frames are dropped once the memory budget is used up.
+type Rewind struct {
+	vm     *VM
+	budget int
+	// latest is the state recorded most recently
+	latest []byte
+	// deltas turn each state into the one before it, the oldest first
+	deltas [][]byte
+	used   int
+	held   bool
+}
+
+// NewRewind records the states of vm, using up to budget bytes.
+func NewRewind(vm *VM, budget int) *Rewind {
+	return &Rewind{vm: vm, budget: budget}
+}
+
+// Record saves the state of the VM, and is called at the end of every frame. If the state can't be
+// saved, the frame isn't recorded.
+func (r *Rewind) Record() error {
+	var state bytes.Buffer
+	if err := r.vm.SaveState(&state); err != nil {
+		return err
+	}
+	if r.latest != nil {
+		delta := diff(state.Bytes(), r.latest)
+		r.deltas = append(r.deltas, delta)
+		r.used += len(delta)
+	}
+	r.used += state.Len() - len(r.latest)
+	r.latest = state.Bytes()
+	for r.used > r.budget && len(r.deltas) > 0 {
+		r.used -= len(r.deltas[0])
+		r.deltas[0] = nil
+		r.deltas = r.deltas[1:]
+	}
+	return nil
+}
+
+// StepBack restores the state recorded the frame before the latest one, returning false if there
+// isn't one.
+func (r *Rewind) StepBack() (bool, error) {
+	if len(r.deltas) == 0 {
+		return false, nil
+	}
+	last := len(r.deltas) - 1
+	previous, err := patch(r.latest, r.deltas[last])
+	if err != nil {
+		return false, err
+	}
+	if err := r.vm.LoadState(bytes.NewReader(previous)); err != nil {
+		return false, err
+	}
+	r.used += len(previous) - len(r.latest) - len(r.deltas[last])
+	r.latest = previous
+	r.deltas[last] = nil
+	r.deltas = r.deltas[:last]
+	return true, nil
+}
+
+// Frames is the number of frames that can be rewound.
+func (r *Rewind) Frames() int {
+	return len(r.deltas)
+}
+
+// Used is the number of bytes taken by the recorded states.
+func (r *Rewind) Used() int {
+	return r.used
+}
+
+// SetRewinding is called as the rewind key is pressed and released. While it is held, Run steps
+// back a frame at a time rather than running the program.
+func (r *Rewind) SetRewinding(held bool) {
+	r.held = held
+}
+
+func (r *Rewind) Rewinding() bool {
+	return r.held
+}
+
+// diff encodes the bytes that change to turn from into to, as runs of a count of unchanged bytes,
+// a count of changed bytes and the changed bytes XORed together. States of different sizes are
+// stored whole after a zero byte.
+func diff(from []byte, to []byte) []byte {
+	if len(from) != len(to) {
+		return append([]byte{0}, to...)
+	}
+	delta := []byte{1}
+	start := 0
+	for n := 0; n < len(to); {
+		if from[n] == to[n] {
+			n++
+			continue
+		}
+		end := n
+		for end < len(to) && from[end] != to[end] {
+			end++
+		}
+		delta = appendUvarint(delta, uint64(n-start))
+		delta = appendUvarint(delta, uint64(end-n))
+		for ; n < end; n++ {
+			delta = append(delta, from[n]^to[n])
+		}
+		start = end
+	}
+	return delta
+}
+
+func appendUvarint(buffer []byte, value uint64) []byte {
+	var encoded [binary.MaxVarintLen64]byte
+	return append(buffer, encoded[:binary.PutUvarint(encoded[:], value)]...)
+}
+
+func patch(from []byte, delta []byte) ([]byte, error) {
+	if len(delta) == 0 {
+		return nil, errors.New("empty rewind delta")
+	}
+	if delta[0] == 0 {
+		return delta[1:], nil
+	}
+	to := append([]byte(nil), from...)
+	reader := bytes.NewReader(delta[1:])
+	position := 0
+	for reader.Len() > 0 {
+		skip, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return nil, err
+		}
+		length, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return nil, err
+		}
+		position += int(skip)
+		if position+int(length) > len(to) {
+			return nil, errors.New("rewind delta is longer than the state")
+		}
+		for end := position + int(length); position < end; position++ {
+			value, err := reader.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			to[position] ^= value
+		}
+	}
+	return to, nil
+}
@@ -0,0 +1,191 @@
+package chip8
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type RewindTestSuite struct {
+	suite.Suite
+	vm      *VM
+	display *mockDisplay
+	rewind  *Rewind
+	code    []byte
+}
+
+func (suite *RewindTestSuite) SetupTest() {
+	suite.display = &mockDisplay{eventType: KeyboardEvent}
+	suite.vm = NewVM(suite.display, MockRandom{55}, QuirksCosmacVIP)
+	suite.vm.SetClock(NewVirtualClock())
+	suite.rewind = NewRewind(suite.vm, 1<<20)
+
+	// Draws a digit that moves right while key 5 is held, and down every frame
+	a := NewAssembler()
+	a.SetRegister(3, 5)
+	a.Label("loop")
+	a.SkipIfKeyNotPressed(3)
+	a.AddToRegister(0, 1)
+	a.AddToRegister(1, 1)
+	a.Random(2, 0x0F)
+	a.FontChar(2)
+	a.Display(0, 1, 5)
+	a.JumpTo("loop")
+	code, err := a.Assemble()
+	suite.Require().NoError(err)
+	suite.code = code
+	suite.vm.Load(code)
+}
+
+func inputForFrame(frame int) uint16 {
+	if frame%3 == 0 {
+		return keyBit(5)
+	}
+	return 0
+}
+
+// runFrame runs the frame with the input for it and records it, returning a copy of the pixels.
+func (suite *RewindTestSuite) runFrame(frame int) [][]byte {
+	suite.vm.Keypad().Set(inputForFrame(frame), 0)
+	_, err := suite.vm.RunFrame()
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.rewind.Record())
+	return suite.vm.State().Display.Pixels
+}
+
+func (suite *RewindTestSuite) TestRewindAndReplayGivesTheSamePixels() {
+	var history [][][]byte
+	for frame := 0; frame < 30; frame++ {
+		history = append(history, suite.runFrame(frame))
+	}
+	suite.Equal(29, suite.rewind.Frames())
+
+	for n := 0; n < 10; n++ {
+		ok, err := suite.rewind.StepBack()
+		suite.Require().NoError(err)
+		suite.True(ok)
+	}
+	suite.Equal(history[19], suite.vm.DisplayBuffer().Pixels)
+
+	for frame := 20; frame < 30; frame++ {
+		suite.Equal(history[frame], suite.runFrame(frame), "frame %d", frame)
+	}
+}
+
+func (suite *RewindTestSuite) TestStepBackRestoresTheWholeState() {
+	suite.runFrame(0)
+	suite.runFrame(1)
+	before := suite.vm.State()
+	suite.runFrame(2)
+
+	suite.rewind.StepBack()
+
+	suite.Equal(before, suite.vm.State())
+}
+
+func (suite *RewindTestSuite) TestNothingToStepBackTo() {
+	ok, err := suite.rewind.StepBack()
+	suite.NoError(err)
+	suite.False(ok)
+
+	suite.runFrame(0)
+	ok, _ = suite.rewind.StepBack()
+	suite.False(ok)
+}
+
+func (suite *RewindTestSuite) TestOldestFramesAreDroppedToFitTheBudget() {
+	var state bytes.Buffer
+	suite.vm.SaveState(&state)
+	suite.rewind = NewRewind(suite.vm, state.Len()+200)
+
+	for frame := 0; frame < 100; frame++ {
+		suite.runFrame(frame)
+	}
+
+	suite.LessOrEqual(suite.rewind.Used(), state.Len()+200)
+	suite.Greater(suite.rewind.Frames(), 0)
+	suite.Less(suite.rewind.Frames(), 99)
+}
+
+func (suite *RewindTestSuite) TestFramesAreDeltaCompressed() {
+	var state bytes.Buffer
+	suite.vm.SaveState(&state)
+
+	for frame := 0; frame < 50; frame++ {
+		suite.runFrame(frame)
+	}
+
+	suite.Less(suite.rewind.Used(), 2*state.Len())
+}
+
+func (suite *RewindTestSuite) TestChangeOfResolution() {
+	suite.runFrame(0)
+	suite.vm.DisplayBuffer().SetHighResolution(true)
+	suite.rewind.Record()
+
+	suite.rewind.StepBack()
+
+	suite.False(suite.vm.DisplayBuffer().HighResolution())
+	suite.Equal(64, suite.vm.DisplayBuffer().Width())
+}
+
+func (suite *RewindTestSuite) TestRunStepsBackWhileRewinding() {
+	suite.vm.SetRewind(suite.rewind)
+	for frame := 0; frame < 5; frame++ {
+		suite.runFrame(frame)
+	}
+	suite.rewind.SetRewinding(true)
+	suite.display.setPollEvents(QuitEvent)
+
+	suite.NoError(suite.vm.Run())
+
+	suite.Equal(3, suite.rewind.Frames())
+}
+
+func (suite *RewindTestSuite) TestSeededRandomIsRewound() {
+	suite.vm = NewVM(suite.display, NewSeededRandom(42), QuirksCosmacVIP)
+	suite.vm.SetClock(NewVirtualClock())
+	suite.vm.Load(suite.code)
+	suite.rewind = NewRewind(suite.vm, 1<<20)
+
+	var states []State
+	var memories [][]byte
+	for frame := 0; frame < 30; frame++ {
+		suite.runFrame(frame)
+		states = append(states, suite.vm.State())
+		memories = append(memories, append([]byte(nil), suite.vm.Memory...))
+	}
+	for n := 0; n < 10; n++ {
+		suite.rewind.StepBack()
+	}
+
+	for frame := 20; frame < 30; frame++ {
+		suite.runFrame(frame)
+		suite.Equal(states[frame], suite.vm.State(), "frame %d", frame)
+		suite.Equal(memories[frame], suite.vm.Memory, "frame %d", frame)
+	}
+}
+
+type failingRandom struct {
+	MockRandom
+}
+
+func (failingRandom) MarshalBinary() ([]byte, error) {
+	return nil, errors.New("can't save")
+}
+
+func (suite *RewindTestSuite) TestFrameIsSkippedWhenTheStateCantBeSaved() {
+	suite.runFrame(0)
+	suite.vm.random = failingRandom{}
+
+	_, err := suite.vm.RunFrame()
+	suite.Require().NoError(err)
+
+	suite.EqualError(suite.rewind.Record(), "can't save")
+	suite.Equal(0, suite.rewind.Frames())
+}
+
+func TestRewindSuite(t *testing.T) {
+	suite.Run(t, new(RewindTestSuite))
+}
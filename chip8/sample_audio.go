@@ -0,0 +1,68 @@
+package chip8
+
+const sampleVolume = 32
+
+// AudioSink receives signed 8 bit mono samples, such as a sound device or a WAV file.
+type AudioSink interface {
+	WriteSamples(samples []int8) error
+}
+
+// SampleAudio renders the buzzer a frame at a time, playing the current sound pattern at its pitch
+// and resampling it to the sample rate of the sink.
+type SampleAudio struct {
+	sink       AudioSink
+	sampleRate int
+	on         bool
+	pattern    SoundPattern
+	position   float64
+	frame      []int8
+	err        error
+}
+
+func NewSampleAudio(sink AudioSink, sampleRate int) *SampleAudio {
+	a := new(SampleAudio)
+	a.sink = sink
+	a.sampleRate = sampleRate
+	a.pattern = DefaultSoundPattern
+	a.frame = make([]int8, sampleRate/60)
+	return a
+}
+
+func (a *SampleAudio) SetTone(on bool) {
+	a.on = on
+	if !on {
+		a.position = 0
+	}
+}
+
+func (a *SampleAudio) SetPattern(pattern SoundPattern) {
+	a.pattern = pattern
+}
+
+// EndFrame renders one frame of samples, silence when the buzzer is off, and writes them to the sink.
+func (a *SampleAudio) EndFrame() {
+	step := a.pattern.BitRate() / float64(a.sampleRate)
+	for n := range a.frame {
+		if !a.on {
+			a.frame[n] = 0
+			continue
+		}
+		if a.pattern.Bit(int(a.position)) == 1 {
+			a.frame[n] = sampleVolume
+		} else {
+			a.frame[n] = -sampleVolume
+		}
+		a.position += step
+		if a.position >= soundPatternBits {
+			a.position -= soundPatternBits
+		}
+	}
+	if err := a.sink.WriteSamples(a.frame); err != nil && a.err == nil {
+		a.err = err
+	}
+}
+
+// Err returns the first error from the sink, as a frame of sound has nowhere to report it.
+func (a *SampleAudio) Err() error {
+	return a.err
+}
@@ -0,0 +1,71 @@
+package chip8
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type SampleAudioTestSuite struct {
+	suite.Suite
+}
+
+func (suite *SampleAudioTestSuite) TestBitRateFollowsPitch() {
+	suite.InDelta(4000.0, SoundPattern{Pitch: 64}.BitRate(), 0.001)
+	suite.InDelta(8000.0, SoundPattern{Pitch: 112}.BitRate(), 0.001)
+	suite.InDelta(2000.0, SoundPattern{Pitch: 16}.BitRate(), 0.001)
+}
+
+func (suite *SampleAudioTestSuite) TestPatternBitsAreMostSignificantFirst() {
+	pattern := SoundPattern{Buffer: [16]byte{0x80, 0x01}}
+	suite.Equal(byte(1), pattern.Bit(0))
+	suite.Equal(byte(0), pattern.Bit(1))
+	suite.Equal(byte(1), pattern.Bit(15))
+	suite.Equal(byte(1), pattern.Bit(128))
+}
+
+func (suite *SampleAudioTestSuite) TestSilentWhenToneIsOff() {
+	sink := NewWAVSink(8000)
+	audio := NewSampleAudio(sink, 8000)
+
+	audio.EndFrame()
+
+	suite.Equal(make([]int8, 133), sink.Samples())
+}
+
+func (suite *SampleAudioTestSuite) TestPlaysPatternAtItsPitch() {
+	sink := NewWAVSink(8000)
+	audio := NewSampleAudio(sink, 8000)
+	pattern := SoundPattern{Pitch: 64}
+	pattern.Buffer[0] = 0b11001100
+	audio.SetPattern(pattern)
+	audio.SetTone(true)
+
+	audio.EndFrame()
+
+	// 4000 bits a second at 8000 samples a second plays each bit twice
+	samples := sink.Samples()
+	suite.Equal([]int8{32, 32, 32, 32, -32, -32, -32, -32, 32, 32}, samples[:10])
+	suite.Equal(int8(-32), samples[20])
+}
+
+func (suite *SampleAudioTestSuite) TestWAVFile() {
+	sink := NewWAVSink(22050)
+	sink.WriteSamples([]int8{0, 32, -32})
+	var file bytes.Buffer
+
+	written, err := sink.WriteTo(&file)
+
+	suite.NoError(err)
+	suite.Equal(int64(47), written)
+	suite.Equal([]byte("RIFF"), file.Bytes()[0:4])
+	suite.Equal([]byte{39, 0, 0, 0}, file.Bytes()[4:8])
+	suite.Equal([]byte("WAVEfmt "), file.Bytes()[8:16])
+	suite.Equal([]byte{0x22, 0x56, 0, 0}, file.Bytes()[24:28])
+	suite.Equal([]byte("data"), file.Bytes()[36:40])
+	suite.Equal([]byte{3, 0, 0, 0, 128, 160, 96}, file.Bytes()[40:])
+}
+
+func TestSampleAudioSuite(t *testing.T) {
+	suite.Run(t, new(SampleAudioTestSuite))
+}
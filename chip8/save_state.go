@@ -0,0 +1,210 @@
+package chip8
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// A save state starts with the magic number, the version of the format and the hash of the ROM it
+// was saved from, followed by the fields of savedMachine, the state of the random generator with its
+// length, the memory and the pixels, little endian.
+const saveStateMagic = "C8SS"
+const saveStateVersion = 1
+
+type saveStateHeader struct {
+	Magic   [4]byte
+	Version uint16
+	ROMHash [sha256.Size]byte
+}
+
+type savedMachine struct {
+	Registers        [16]byte
+	IndexRegister    uint16
+	PC               uint16
+	Stack            [16]uint16
+	SP               uint8
+	DelayTimer       byte
+	SoundTimer       byte
+	Quirks           uint16
+	RPLFlags         [16]byte
+	SoundPattern     [16]byte
+	Pitch            byte
+	WaitingForVBlank bool
+	WaitingForKey    bool
+	KeyRegister      byte
+	KeysPressed      uint16
+	KeysReleased     uint16
+	HighResolution   bool
+	Planes           byte
+	MemorySize       uint32
+}
+
+// flags lists the quirks in the order of their bits in a save state. New quirks go on the end.
+func (q *Quirks) flags() []*bool {
+	return []*bool{
+		&q.ShiftUsesVY,
+		&q.LoadStoreIncrementsIndex,
+		&q.JumpUsesVX,
+		&q.LogicResetsVF,
+		&q.WrapSprites,
+		&q.DisplayWait,
+		&q.ExtendedMemory,
+	}
+}
+
+func (q Quirks) bits() uint16 {
+	var bits uint16
+	for n, flag := range q.flags() {
+		if *flag {
+			bits |= 1 << n
+		}
+	}
+	return bits
+}
+
+func quirksFromBits(bits uint16) Quirks {
+	var q Quirks
+	for n, flag := range q.flags() {
+		*flag = bits&(1<<n) != 0
+	}
+	return q
+}
+
+// ROMHash is the SHA-256 of the program passed to Load.
+func (v *VM) ROMHash() [sha256.Size]byte {
+	return v.romHash
+}
+
+// SaveState writes everything needed to carry on from this point: memory, registers, stack, timers,
+// quirks, the screen, the keypad, the random generator if it can be saved, and whether the VM is
+// waiting for a key or the vertical blank.
+func (v *VM) SaveState(w io.Writer) error {
+	header := saveStateHeader{Version: saveStateVersion, ROMHash: v.romHash}
+	copy(header.Magic[:], saveStateMagic)
+	machine := savedMachine{
+		Registers:        v.registers,
+		IndexRegister:    v.indexRegister,
+		PC:               v.pc,
+		Stack:            v.theStack.address,
+		SP:               uint8(v.theStack.index),
+		DelayTimer:       v.timers.Delay(),
+		SoundTimer:       v.timers.Sound(),
+		Quirks:           v.quirks.bits(),
+		RPLFlags:         v.rplFlags,
+		SoundPattern:     v.soundPattern.Buffer,
+		Pitch:            v.soundPattern.Pitch,
+		WaitingForVBlank: v.waitingForVBlank,
+		WaitingForKey:    v.waitingForKey,
+		KeyRegister:      v.keyRegister,
+		KeysPressed:      v.keypad.Pressed(),
+		KeysReleased:     v.keypad.Released(),
+		HighResolution:   v.displayBuffer.highResolution,
+		Planes:           v.displayBuffer.planes,
+		MemorySize:       uint32(len(v.Memory)),
+	}
+	var random []byte
+	if marshaler, ok := v.random.(encoding.BinaryMarshaler); ok {
+		var err error
+		if random, err = marshaler.MarshalBinary(); err != nil {
+			return err
+		}
+	}
+
+	out := bufio.NewWriter(w)
+	for _, data := range []interface{}{header, machine, uint16(len(random)), random, v.Memory} {
+		if err := binary.Write(out, binary.LittleEndian, data); err != nil {
+			return err
+		}
+	}
+	for _, row := range v.displayBuffer.Pixels {
+		if _, err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+// LoadState restores a state written by SaveState. The VM must have loaded the same ROM, and is left
+// unchanged if the state can't be read.
+func (v *VM) LoadState(r io.Reader) error {
+	var header saveStateHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return fmt.Errorf("reading save state: %w", err)
+	}
+	if string(header.Magic[:]) != saveStateMagic {
+		return errors.New("not a save state")
+	}
+	if header.Version != saveStateVersion {
+		return fmt.Errorf("unsupported save state version %d", header.Version)
+	}
+	if header.ROMHash != v.romHash {
+		return errors.New("save state is for a different ROM")
+	}
+
+	var machine savedMachine
+	if err := binary.Read(r, binary.LittleEndian, &machine); err != nil {
+		return fmt.Errorf("reading save state: %w", err)
+	}
+	if machine.SP > uint8(len(machine.Stack)) {
+		return fmt.Errorf("invalid stack pointer %d in save state", machine.SP)
+	}
+	if machine.MemorySize != memorySize && machine.MemorySize != extendedMemorySize {
+		return fmt.Errorf("invalid memory size %d in save state", machine.MemorySize)
+	}
+	var length uint16
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		return fmt.Errorf("reading save state: %w", err)
+	}
+	random := make([]byte, length)
+	if _, err := io.ReadFull(r, random); err != nil {
+		return fmt.Errorf("reading save state: %w", err)
+	}
+	memory := make([]byte, machine.MemorySize)
+	if _, err := io.ReadFull(r, memory); err != nil {
+		return fmt.Errorf("reading save state: %w", err)
+	}
+	width, height := lowResolutionWidth, lowResolutionHeight
+	if machine.HighResolution {
+		width, height = highResolutionWidth, highResolutionHeight
+	}
+	pixels := make([][]byte, height)
+	for y := range pixels {
+		pixels[y] = make([]byte, width)
+		if _, err := io.ReadFull(r, pixels[y]); err != nil {
+			return fmt.Errorf("reading save state: %w", err)
+		}
+	}
+
+	if unmarshaler, ok := v.random.(encoding.BinaryUnmarshaler); ok && len(random) > 0 {
+		if err := unmarshaler.UnmarshalBinary(random); err != nil {
+			return fmt.Errorf("restoring save state: %w", err)
+		}
+	}
+	v.registers = machine.Registers
+	v.indexRegister = machine.IndexRegister
+	v.pc = machine.PC
+	v.theStack.address = machine.Stack
+	v.theStack.index = int(machine.SP)
+	v.timers.setDelay(machine.DelayTimer)
+	v.timers.setSound(machine.SoundTimer)
+	v.quirks = quirksFromBits(machine.Quirks)
+	v.rplFlags = machine.RPLFlags
+	v.soundPattern = SoundPattern{Buffer: machine.SoundPattern, Pitch: machine.Pitch}
+	v.audio.SetPattern(v.soundPattern)
+	v.updateTone()
+	v.waitingForVBlank = machine.WaitingForVBlank
+	v.waitingForKey = machine.WaitingForKey
+	v.keyRegister = machine.KeyRegister & 0xF
+	v.keypad.Set(machine.KeysPressed, machine.KeysReleased)
+	v.Memory = memory
+	v.displayBuffer.SetHighResolution(machine.HighResolution)
+	v.displayBuffer.Pixels = pixels
+	v.displayBuffer.SelectPlanes(machine.Planes)
+	v.displayBuffer.SetSpriteWrapping(v.quirks.WrapSprites)
+	return nil
+}
@@ -0,0 +1,189 @@
+package chip8
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type SaveStateTestSuite struct {
+	suite.Suite
+	code []byte
+}
+
+func (suite *SaveStateTestSuite) SetupTest() {
+	a := NewAssembler()
+	a.SetRegister(1, 5)
+	a.SetDelayTimer(1)
+	a.Label("loop")
+	a.AddToRegister(0, 1)
+	a.FontChar(0)
+	a.Display(0, 0, 5)
+	a.CallLabel("sub")
+	a.JumpTo("loop")
+	a.Label("sub")
+	a.Store(2)
+	a.Return()
+	code, err := a.Assemble()
+	suite.Require().NoError(err)
+	suite.code = code
+}
+
+func (suite *SaveStateTestSuite) newVM(quirks Quirks) *VM {
+	vm := NewVM(&mockDisplay{eventType: KeyboardEvent}, MockRandom{55}, quirks)
+	vm.SetClock(NewVirtualClock())
+	vm.Load(suite.code)
+	return vm
+}
+
+func (suite *SaveStateTestSuite) runFrames(vm *VM, frames int) {
+	for n := 0; n < frames; n++ {
+		_, err := vm.RunFrame()
+		suite.Require().NoError(err)
+	}
+}
+
+func (suite *SaveStateTestSuite) TestRestoredVMCarriesOnTheSame() {
+	original := suite.newVM(QuirksCosmacVIP)
+	suite.runFrames(original, 7)
+	original.RunCycles(3)
+	var saved bytes.Buffer
+	suite.Require().NoError(original.SaveState(&saved))
+
+	restored := suite.newVM(QuirksCosmacVIP)
+	suite.Require().NoError(restored.LoadState(&saved))
+	suite.Equal(original.State(), restored.State())
+	suite.Equal(original.Memory, restored.Memory)
+
+	suite.runFrames(original, 5)
+	suite.runFrames(restored, 5)
+	suite.Equal(original.State(), restored.State())
+	suite.Equal(original.Memory, restored.Memory)
+}
+
+func (suite *SaveStateTestSuite) TestQuirksAndExtendedMemoryAreRestored() {
+	original := suite.newVM(QuirksXOChip)
+	original.Memory[0xF000] = 0x42
+	original.DisplayBuffer().SetHighResolution(true)
+	original.DisplayBuffer().SelectPlanes(3)
+	var saved bytes.Buffer
+	suite.Require().NoError(original.SaveState(&saved))
+
+	restored := suite.newVM(QuirksCosmacVIP)
+	suite.Require().NoError(restored.LoadState(&saved))
+
+	suite.Equal(QuirksXOChip, restored.State().Quirks)
+	suite.Len(restored.Memory, extendedMemorySize)
+	suite.Equal(byte(0x42), restored.Memory[0xF000])
+	suite.True(restored.DisplayBuffer().HighResolution())
+	suite.Equal(128, restored.DisplayBuffer().Width())
+	suite.Equal(byte(3), restored.DisplayBuffer().SelectedPlanes())
+}
+
+func (suite *SaveStateTestSuite) TestWaitingForKeyIsRestored() {
+	a := NewAssembler()
+	a.SetRegister(1, 5)
+	a.GetKey(2)
+	suite.code, _ = a.Assemble()
+	original := suite.newVM(QuirksCosmacVIP)
+	suite.runFrames(original, 2)
+	var saved bytes.Buffer
+	suite.Require().NoError(original.SaveState(&saved))
+
+	restored := suite.newVM(QuirksCosmacVIP)
+	suite.Require().NoError(restored.LoadState(&saved))
+	suite.True(restored.State().WaitingForKey)
+	suite.Equal(byte(2), restored.State().KeyRegister)
+	suite.runFrames(restored, 2)
+	suite.Equal(uint16(0x204), restored.PC())
+
+	restored.Keypad().Press(0xA)
+	restored.Keypad().Release(0xA)
+	restored.Step()
+	suite.Equal(byte(0xA), restored.State().Registers[2])
+}
+
+func (suite *SaveStateTestSuite) TestKeypadIsRestored() {
+	original := suite.newVM(QuirksCosmacVIP)
+	original.Keypad().Press(0x3)
+	original.Keypad().Press(0xE)
+	original.Keypad().Release(0x3)
+	var saved bytes.Buffer
+	suite.Require().NoError(original.SaveState(&saved))
+
+	restored := suite.newVM(QuirksCosmacVIP)
+	suite.Require().NoError(restored.LoadState(&saved))
+
+	suite.Equal(uint16(0x4000), restored.Keypad().Pressed())
+	suite.Equal(uint16(0x0008), restored.Keypad().Released())
+}
+
+func (suite *SaveStateTestSuite) TestRandomGeneratorIsRestored() {
+	for _, name := range RandomNames {
+		random, _ := RandomByName(name, 99)
+		vm := NewVM(&mockDisplay{}, random, QuirksCosmacVIP)
+		vm.Load(suite.code)
+		random.Generate()
+		var saved bytes.Buffer
+		suite.Require().NoError(vm.SaveState(&saved))
+		expected := []byte{random.Generate(), random.Generate(), random.Generate()}
+
+		suite.Require().NoError(vm.LoadState(&saved))
+
+		suite.Equal(expected, []byte{random.Generate(), random.Generate(), random.Generate()}, name)
+	}
+}
+
+func (suite *SaveStateTestSuite) TestDifferentRandomGeneratorIsRejected() {
+	original := NewVM(&mockDisplay{}, NewTableRandom(1), QuirksCosmacVIP)
+	original.Load(suite.code)
+	var saved bytes.Buffer
+	suite.Require().NoError(original.SaveState(&saved))
+
+	vm := suite.newVM(QuirksCosmacVIP)
+	vm.random = NewSeededRandom(1)
+
+	suite.EqualError(vm.LoadState(&saved), "restoring save state: state is for a different random generator")
+}
+
+func (suite *SaveStateTestSuite) TestDifferentROMIsRejected() {
+	original := suite.newVM(QuirksCosmacVIP)
+	var saved bytes.Buffer
+	suite.Require().NoError(original.SaveState(&saved))
+
+	other := NewVM(&mockDisplay{}, MockRandom{55}, QuirksCosmacVIP)
+	other.Load([]byte{0x12, 0x00})
+
+	suite.EqualError(other.LoadState(&saved), "save state is for a different ROM")
+}
+
+func (suite *SaveStateTestSuite) TestBadHeaders() {
+	vm := suite.newVM(QuirksCosmacVIP)
+	var saved bytes.Buffer
+	suite.Require().NoError(vm.SaveState(&saved))
+
+	notAState := append([]byte("XXXX"), saved.Bytes()[4:]...)
+	suite.EqualError(vm.LoadState(bytes.NewReader(notAState)), "not a save state")
+
+	newerVersion := append([]byte(nil), saved.Bytes()...)
+	newerVersion[4] = 99
+	suite.EqualError(vm.LoadState(bytes.NewReader(newerVersion)), "unsupported save state version 99")
+}
+
+func (suite *SaveStateTestSuite) TestTruncatedStateLeavesTheVMAlone() {
+	original := suite.newVM(QuirksCosmacVIP)
+	suite.runFrames(original, 3)
+	var saved bytes.Buffer
+	suite.Require().NoError(original.SaveState(&saved))
+
+	vm := suite.newVM(QuirksCosmacVIP)
+	before := vm.State()
+	err := vm.LoadState(bytes.NewReader(saved.Bytes()[:saved.Len()-10]))
+
+	suite.Error(err)
+	suite.Equal(before, vm.State())
+}
+
+func TestSaveStateSuite(t *testing.T) {
+	suite.Run(t, new(SaveStateTestSuite))
+}
@@ -0,0 +1,30 @@
+package chip8
+
+import "math"
+
+const soundPatternBits = 128
+const defaultPitch = 64
+
+// SoundPattern is the XO-CHIP waveform: 128 one-bit samples, played most significant bit first and
+// repeated for as long as the sound timer runs.
+type SoundPattern struct {
+	Buffer [16]byte
+	Pitch  byte
+}
+
+// DefaultSoundPattern is played until a program loads its own, a 500 Hz square wave like a plain buzzer.
+var DefaultSoundPattern = SoundPattern{
+	Buffer: [16]byte{0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0},
+	Pitch:  defaultPitch,
+}
+
+// BitRate is the number of pattern bits played each second, 4000 * 2^((pitch-64)/48).
+func (p SoundPattern) BitRate() float64 {
+	return 4000 * math.Pow(2, (float64(p.Pitch)-defaultPitch)/48)
+}
+
+// Bit returns the sample at position n of the pattern, wrapping around after 128 bits.
+func (p SoundPattern) Bit(n int) byte {
+	n %= soundPatternBits
+	return GetValueAtPosition(7-n%8, p.Buffer[n/8])
+}
@@ -0,0 +1,535 @@
+package chip8
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ProgramStart is the address programs are loaded at, and where assembly starts.
+const ProgramStart = 0x200
+
+// AssemblyError reports a mistake in assembly source and where it was found.
+type AssemblyError struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *AssemblyError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+type operandKind int
+
+const (
+	registerOperand operandKind = iota
+	valueOperand
+	longOperand
+	indexOperand
+	indirectOperand
+	delayOperand
+	soundOperand
+	keyOperand
+	fontOperand
+	largeFontOperand
+	bcdOperand
+	flagsOperand
+)
+
+// specialOperands are the reserved names that can be used as operands, such as the I in LD I, 0x300.
+var specialOperands = map[string]operandKind{
+	"I":  indexOperand,
+	"DT": delayOperand,
+	"ST": soundOperand,
+	"K":  keyOperand,
+	"F":  fontOperand,
+	"HF": largeFontOperand,
+	"B":  bcdOperand,
+	"R":  flagsOperand,
+}
+
+type operand struct {
+	kind     operandKind
+	register byte
+	value    expression
+	at       sourcePosition
+}
+
+// sourceInstruction is one form of a mnemonic, with the operands it takes written as in the
+// documentation: Vx and Vy are registers, V0 only V0, n a nibble, nn a byte, nnn an address and
+// LONG nnnn a 16 bit address. Other operands are the reserved names such as I, DT and [I].
+type sourceInstruction struct {
+	mnemonic string
+	operands string
+	emit     func(a *Assembler, args []int)
+}
+
+var sourceInstructions = []sourceInstruction{
+	{"CLS", "", func(a *Assembler, args []int) { a.ClearScreen() }},
+	{"RET", "", func(a *Assembler, args []int) { a.Return() }},
+	{"SCD", "n", func(a *Assembler, args []int) { a.ScrollDown(byte(args[0])) }},
+	{"SCR", "", func(a *Assembler, args []int) { a.ScrollRight() }},
+	{"SCL", "", func(a *Assembler, args []int) { a.ScrollLeft() }},
+	{"EXIT", "", func(a *Assembler, args []int) { a.Exit() }},
+	{"LOW", "", func(a *Assembler, args []int) { a.LowResolution() }},
+	{"HIGH", "", func(a *Assembler, args []int) { a.HighResolution() }},
+	{"JP", "nnn", func(a *Assembler, args []int) { a.Jump(uint16(args[0])) }},
+	{"JP", "V0, nnn", func(a *Assembler, args []int) { a.SetJumpWithOffset(uint16(args[1])) }},
+	{"CALL", "nnn", func(a *Assembler, args []int) { a.Sub(uint16(args[0])) }},
+	{"SE", "Vx, nn", func(a *Assembler, args []int) { a.SkipIfEqual(byte(args[0]), byte(args[1])) }},
+	{"SE", "Vx, Vy", func(a *Assembler, args []int) { a.SkipIfRegistersEqual(byte(args[0]), byte(args[1])) }},
+	{"SNE", "Vx, nn", func(a *Assembler, args []int) { a.SkipIfNotEqual(byte(args[0]), byte(args[1])) }},
+	{"SNE", "Vx, Vy", func(a *Assembler, args []int) { a.SkipIfRegistersNotEqual(byte(args[0]), byte(args[1])) }},
+	{"LD", "Vx, nn", func(a *Assembler, args []int) { a.SetRegister(byte(args[0]), byte(args[1])) }},
+	{"LD", "Vx, Vy", func(a *Assembler, args []int) { a.Set(byte(args[0]), byte(args[1])) }},
+	{"LD", "I, nnn", func(a *Assembler, args []int) { a.SetIndexRegister(uint16(args[1])) }},
+	{"LD", "I, LONG nnnn", func(a *Assembler, args []int) { a.LongSetIndexRegister(uint16(args[1])) }},
+	{"LD", "Vx, DT", func(a *Assembler, args []int) { a.GetDelayTimer(byte(args[0])) }},
+	{"LD", "Vx, K", func(a *Assembler, args []int) { a.GetKey(byte(args[0])) }},
+	{"LD", "DT, Vx", func(a *Assembler, args []int) { a.SetDelayTimer(byte(args[1])) }},
+	{"LD", "ST, Vx", func(a *Assembler, args []int) { a.SetSoundTimer(byte(args[1])) }},
+	{"LD", "F, Vx", func(a *Assembler, args []int) { a.FontChar(byte(args[1])) }},
+	{"LD", "HF, Vx", func(a *Assembler, args []int) { a.LargeFontChar(byte(args[1])) }},
+	{"LD", "B, Vx", func(a *Assembler, args []int) { a.BCD(byte(args[1])) }},
+	{"LD", "[I], Vx", func(a *Assembler, args []int) { a.Store(byte(args[1])) }},
+	{"LD", "Vx, [I]", func(a *Assembler, args []int) { a.Load(byte(args[0])) }},
+	{"LD", "R, Vx", func(a *Assembler, args []int) { a.StoreFlags(byte(args[1])) }},
+	{"LD", "Vx, R", func(a *Assembler, args []int) { a.LoadFlags(byte(args[0])) }},
+	{"ADD", "Vx, nn", func(a *Assembler, args []int) { a.AddToRegister(byte(args[0]), byte(args[1])) }},
+	{"ADD", "Vx, Vy", func(a *Assembler, args []int) { a.Add(byte(args[0]), byte(args[1])) }},
+	{"ADD", "I, Vx", func(a *Assembler, args []int) { a.AddToIndex(byte(args[1])) }},
+	{"OR", "Vx, Vy", func(a *Assembler, args []int) { a.Or(byte(args[0]), byte(args[1])) }},
+	{"AND", "Vx, Vy", func(a *Assembler, args []int) { a.And(byte(args[0]), byte(args[1])) }},
+	{"XOR", "Vx, Vy", func(a *Assembler, args []int) { a.Xor(byte(args[0]), byte(args[1])) }},
+	{"SUB", "Vx, Vy", func(a *Assembler, args []int) { a.Subtract(byte(args[0]), byte(args[1])) }},
+	{"SHR", "Vx", func(a *Assembler, args []int) { a.ShiftRight(byte(args[0]), byte(args[0])) }},
+	{"SHR", "Vx, Vy", func(a *Assembler, args []int) { a.ShiftRight(byte(args[0]), byte(args[1])) }},
+	{"SUBN", "Vx, Vy", func(a *Assembler, args []int) { a.SubtractLast(byte(args[0]), byte(args[1])) }},
+	{"SHL", "Vx", func(a *Assembler, args []int) { a.ShiftLeft(byte(args[0]), byte(args[0])) }},
+	{"SHL", "Vx, Vy", func(a *Assembler, args []int) { a.ShiftLeft(byte(args[0]), byte(args[1])) }},
+	{"RND", "Vx, nn", func(a *Assembler, args []int) { a.Random(byte(args[0]), byte(args[1])) }},
+	{"DRW", "Vx, Vy, n", func(a *Assembler, args []int) { a.Display(byte(args[0]), byte(args[1]), byte(args[2])) }},
+	{"SKP", "Vx", func(a *Assembler, args []int) { a.SkipIfKeyPressed(byte(args[0])) }},
+	{"SKNP", "Vx", func(a *Assembler, args []int) { a.SkipIfKeyNotPressed(byte(args[0])) }},
+	{"SAVE", "Vx, Vy", func(a *Assembler, args []int) { a.SaveRange(byte(args[0]), byte(args[1])) }},
+	{"LOAD", "Vx, Vy", func(a *Assembler, args []int) { a.LoadRange(byte(args[0]), byte(args[1])) }},
+	{"PLANE", "n", func(a *Assembler, args []int) { a.SelectPlanes(byte(args[0])) }},
+	{"AUDIO", "", func(a *Assembler, args []int) { a.LoadAudioPattern() }},
+	{"PITCH", "Vx", func(a *Assembler, args []int) { a.SetPitch(byte(args[0])) }},
+}
+
+func (s *sourceInstruction) patterns() []string {
+	if s.operands == "" {
+		return nil
+	}
+	return strings.Split(s.operands, ", ")
+}
+
+func (s *sourceInstruction) size() int {
+	if strings.Contains(s.operands, "LONG") {
+		return 4
+	}
+	return 2
+}
+
+func (s *sourceInstruction) matches(operands []operand) bool {
+	patterns := s.patterns()
+	if len(patterns) != len(operands) {
+		return false
+	}
+	for n, pattern := range patterns {
+		if !operandMatches(pattern, operands[n]) {
+			return false
+		}
+	}
+	return true
+}
+
+func operandMatches(pattern string, o operand) bool {
+	switch pattern {
+	case "Vx", "Vy":
+		return o.kind == registerOperand
+	case "V0":
+		return o.kind == registerOperand && o.register == 0
+	case "n", "nn", "nnn":
+		return o.kind == valueOperand
+	case "LONG nnnn":
+		return o.kind == longOperand
+	case "[I]":
+		return o.kind == indirectOperand
+	}
+	return o.kind == specialOperands[pattern]
+}
+
+// operandRanges are the values each kind of number operand accepts. Bytes may also be negative.
+var operandRanges = map[string][2]int{
+	"n":         {0, 0xF},
+	"nn":        {-0x80, 0xFF},
+	"nnn":       {0, 0xFFF},
+	"LONG nnnn": {0, 0xFFFF},
+}
+
+func registerNumber(name string) (byte, bool) {
+	if len(name) != 2 || (name[0] != 'V' && name[0] != 'v') {
+		return 0, false
+	}
+	value, err := parseNumber("0x" + name[1:])
+	if err != nil {
+		return 0, false
+	}
+	return byte(value), true
+}
+
+type statementKind int
+
+const (
+	instructionStatement statementKind = iota
+	byteStatement
+	wordStatement
+	originStatement
+)
+
+type dataItem struct {
+	text  string
+	value expression
+}
+
+type statement struct {
+	kind        statementKind
+	at          sourcePosition
+	address     int
+	instruction *sourceInstruction
+	operands    []operand
+	data        []dataItem
+	origin      int
+}
+
+type sourceSymbol struct {
+	at        sourcePosition
+	value     int
+	constant  expression
+	resolved  bool
+	resolving bool
+}
+
+type sourceAssembler struct {
+	address    int
+	statements []statement
+	symbols    map[string]*sourceSymbol
+}
+
+// AssembleSource assembles a program written with mnemonics, returning the bytes to load at 0x200.
+// Errors are *AssemblyError, giving the line and column of the mistake.
+func AssembleSource(source io.Reader) ([]byte, error) {
+	return AssembleSourceFile("", source)
+}
+
+// AssembleSourceFile is AssembleSource with the file name included in any error.
+func AssembleSourceFile(filename string, source io.Reader) ([]byte, error) {
+	s := &sourceAssembler{address: ProgramStart, symbols: make(map[string]*sourceSymbol)}
+	scanner := bufio.NewScanner(source)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if err := s.parseLine(scanner.Text(), lineNumber); err != nil {
+			err.File = filename
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	code, err := s.emit()
+	if err != nil {
+		err.File = filename
+		return nil, err
+	}
+	return code, nil
+}
+
+func (s *sourceAssembler) parseLine(line string, lineNumber int) *AssemblyError {
+	tokens, err := tokenize(line, lineNumber)
+	if err != nil {
+		return err
+	}
+	p := &lineParser{tokens: tokens, line: lineNumber, end: len(line) + 1}
+
+	if len(tokens) >= 2 && tokens[0].kind == identifierToken && tokens[1].is(":") {
+		label := p.next()
+		p.next()
+		if err := s.define(label.text, p.positionOf(label), &sourceSymbol{value: s.address, resolved: true}); err != nil {
+			return err
+		}
+	}
+	if p.atEnd() {
+		return nil
+	}
+
+	name := p.next()
+	at := p.positionOf(name)
+	if name.kind != identifierToken {
+		return at.errorf("expected an instruction but found %s", p.describe(name))
+	}
+	if p.peek().is("=") || (p.peek().kind == identifierToken && strings.EqualFold(p.peek().text, "equ")) {
+		p.next()
+		value, err := p.parseExpression()
+		if err != nil {
+			return err
+		}
+		if err := s.define(name.text, at, &sourceSymbol{constant: value}); err != nil {
+			return err
+		}
+		return p.expectEnd()
+	}
+
+	switch strings.ToLower(name.text) {
+	case "db":
+		return s.parseData(p, statement{kind: byteStatement, at: at, address: s.address}, 1)
+	case "dw":
+		return s.parseData(p, statement{kind: wordStatement, at: at, address: s.address}, 2)
+	case "org":
+		return s.parseOrigin(p, at)
+	}
+	return s.parseInstruction(p, name)
+}
+
+func (s *sourceAssembler) define(name string, at sourcePosition, symbol *sourceSymbol) *AssemblyError {
+	if _, ok := registerNumber(name); ok {
+		return at.errorf("register %s cannot be used as a name", name)
+	}
+	if _, ok := specialOperands[strings.ToUpper(name)]; ok {
+		return at.errorf("%s is reserved and cannot be used as a name", name)
+	}
+	if existing, ok := s.symbols[name]; ok {
+		return at.errorf("%s is already defined at line %d", name, existing.at.line)
+	}
+	symbol.at = at
+	s.symbols[name] = symbol
+	return nil
+}
+
+func (s *sourceAssembler) parseData(p *lineParser, st statement, width int) *AssemblyError {
+	for {
+		t := p.peek()
+		if t.kind == stringToken && width == 1 {
+			p.next()
+			st.data = append(st.data, dataItem{text: t.text})
+			s.address += len(t.text)
+		} else {
+			value, err := p.parseExpression()
+			if err != nil {
+				return err
+			}
+			st.data = append(st.data, dataItem{value: value})
+			s.address += width
+		}
+		if p.atEnd() {
+			break
+		}
+		if separator := p.next(); !separator.is(",") {
+			return p.positionOf(separator).errorf("expected \",\" but found %s", p.describe(separator))
+		}
+	}
+	s.statements = append(s.statements, st)
+	return nil
+}
+
+// parseOrigin moves the address of the following code, so its expression can only use symbols
+// that are already defined.
+func (s *sourceAssembler) parseOrigin(p *lineParser, at sourcePosition) *AssemblyError {
+	value, err := p.parseExpression()
+	if err != nil {
+		return err
+	}
+	if err := p.expectEnd(); err != nil {
+		return err
+	}
+	origin, err := value.evaluate(s)
+	if err != nil {
+		return err
+	}
+	if origin > extendedMemorySize {
+		return value.position().errorf("org 0x%X is outside memory", origin)
+	}
+	if origin < s.address {
+		return value.position().errorf("org 0x%X is before the current address 0x%X", origin, s.address)
+	}
+	s.statements = append(s.statements, statement{kind: originStatement, at: at, address: s.address, origin: origin})
+	s.address = origin
+	return nil
+}
+
+func (s *sourceAssembler) parseInstruction(p *lineParser, name token) *AssemblyError {
+	at := p.positionOf(name)
+	var operands []operand
+	for !p.atEnd() {
+		o, err := p.parseOperand()
+		if err != nil {
+			return err
+		}
+		operands = append(operands, o)
+		if p.atEnd() {
+			break
+		}
+		if separator := p.next(); !separator.is(",") {
+			return p.positionOf(separator).errorf("expected \",\" but found %s", p.describe(separator))
+		}
+	}
+
+	mnemonic := strings.ToUpper(name.text)
+	known := false
+	for n := range sourceInstructions {
+		instruction := &sourceInstructions[n]
+		if instruction.mnemonic != mnemonic {
+			continue
+		}
+		known = true
+		if instruction.matches(operands) {
+			s.statements = append(s.statements, statement{kind: instructionStatement, at: at, address: s.address, instruction: instruction, operands: operands})
+			s.address += instruction.size()
+			return nil
+		}
+	}
+	if !known {
+		return at.errorf("unknown instruction %s", name.text)
+	}
+	return at.errorf("invalid operands for %s", mnemonic)
+}
+
+func (p *lineParser) parseOperand() (operand, *AssemblyError) {
+	t := p.peek()
+	at := p.positionOf(t)
+	if t.is("[") {
+		p.next()
+		index := p.next()
+		closing := p.next()
+		if !strings.EqualFold(index.text, "I") || !closing.is("]") {
+			return operand{}, at.errorf("expected [I]")
+		}
+		return operand{kind: indirectOperand, at: at}, nil
+	}
+	if t.kind == identifierToken {
+		following := token{}
+		if p.index+1 < len(p.tokens) {
+			following = p.tokens[p.index+1]
+		}
+		if following.text == "" || following.is(",") {
+			if register, ok := registerNumber(t.text); ok {
+				p.next()
+				return operand{kind: registerOperand, register: register, at: at}, nil
+			}
+			if kind, ok := specialOperands[strings.ToUpper(t.text)]; ok {
+				p.next()
+				return operand{kind: kind, at: at}, nil
+			}
+		} else if strings.EqualFold(t.text, "LONG") {
+			p.next()
+			value, err := p.parseExpression()
+			return operand{kind: longOperand, value: value, at: at}, err
+		}
+	}
+	value, err := p.parseExpression()
+	return operand{kind: valueOperand, value: value, at: at}, err
+}
+
+func (p *lineParser) expectEnd() *AssemblyError {
+	if !p.atEnd() {
+		t := p.next()
+		return p.positionOf(t).errorf("unexpected %s", p.describe(t))
+	}
+	return nil
+}
+
+// lookup finds the value of a label or constant, evaluating constants the first time they are used.
+func (s *sourceAssembler) lookup(name string, at sourcePosition) (int, *AssemblyError) {
+	symbol, ok := s.symbols[name]
+	if !ok {
+		return 0, at.errorf("undefined symbol %s", name)
+	}
+	if !symbol.resolved {
+		if symbol.resolving {
+			return 0, symbol.at.errorf("%s is defined in terms of itself", name)
+		}
+		symbol.resolving = true
+		value, err := symbol.constant.evaluate(s)
+		symbol.resolving = false
+		if err != nil {
+			return 0, err
+		}
+		symbol.value = value
+		symbol.resolved = true
+	}
+	return symbol.value, nil
+}
+
+// emit is the second pass, when every label has an address and the expressions can be evaluated.
+func (s *sourceAssembler) emit() ([]byte, *AssemblyError) {
+	a := NewAssembler()
+	for _, st := range s.statements {
+		switch st.kind {
+		case instructionStatement:
+			args, err := s.evaluateOperands(st)
+			if err != nil {
+				return nil, err
+			}
+			st.instruction.emit(a, args)
+		case byteStatement, wordStatement:
+			for _, item := range st.data {
+				if item.value == nil {
+					a.Data([]byte(item.text))
+					continue
+				}
+				value, err := item.value.evaluate(s)
+				if err != nil {
+					return nil, err
+				}
+				if st.kind == byteStatement {
+					if value < -0x80 || value > 0xFF {
+						return nil, item.value.position().errorf("value %d does not fit in a byte", value)
+					}
+					a.Data([]byte{byte(value)})
+				} else {
+					if value < -0x8000 || value > 0xFFFF {
+						return nil, item.value.position().errorf("value %d does not fit in a word", value)
+					}
+					a.Data([]byte{extractFirstByte(uint16(value)), extractSecondByte(uint16(value))})
+				}
+			}
+		case originStatement:
+			a.Data(make([]byte, st.origin-st.address))
+		}
+	}
+	code, err := a.Assemble()
+	if err != nil {
+		return nil, &AssemblyError{Message: err.Error()}
+	}
+	return code, nil
+}
+
+func (s *sourceAssembler) evaluateOperands(st statement) ([]int, *AssemblyError) {
+	patterns := st.instruction.patterns()
+	args := make([]int, len(st.operands))
+	for n, o := range st.operands {
+		switch o.kind {
+		case registerOperand:
+			args[n] = int(o.register)
+		case valueOperand, longOperand:
+			value, err := o.value.evaluate(s)
+			if err != nil {
+				return nil, err
+			}
+			limits := operandRanges[patterns[n]]
+			if value < limits[0] || value > limits[1] {
+				return nil, o.value.position().errorf("value %d is out of range for %s", value, patterns[n])
+			}
+			args[n] = value & limits[1]
+		}
+	}
+	return args, nil
+}
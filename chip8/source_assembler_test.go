@@ -0,0 +1,214 @@
+package chip8
+
+import (
+	"github.com/stretchr/testify/suite"
+	"strings"
+	"testing"
+)
+
+type SourceAssemblerTestSuite struct {
+	suite.Suite
+}
+
+func (suite *SourceAssemblerTestSuite) assemble(source string) []byte {
+	code, err := AssembleSource(strings.NewReader(source))
+	suite.Require().NoError(err)
+	return code
+}
+
+func (suite *SourceAssemblerTestSuite) assemblyError(source string) *AssemblyError {
+	_, err := AssembleSource(strings.NewReader(source))
+	suite.Require().Error(err)
+	return err.(*AssemblyError)
+}
+
+func (suite *SourceAssemblerTestSuite) TestInstructionsMatchTheBuilder() {
+	code := suite.assemble(`
+		cls
+		LD V1, 0x20
+		ld v2, V1
+		LD I, 0x300
+		LD I, LONG 0xABCD
+		LD [I], V3
+		LD V4, [I]
+		LD F, V5
+		LD HF, V5
+		LD B, V6
+		LD DT, V7
+		LD ST, V7
+		LD V8, DT
+		LD V9, K
+		LD R, V2
+		LD V2, R
+		ADD V1, 1
+		ADD V1, V2
+		ADD I, V3
+		SE V1, 2
+		SNE V1, V2
+		SHR V1
+		SHL VA, VB
+		DRW V0, V1, 5
+		JP V0, 0x400
+		RND VF, 0xFF
+		PLANE 3
+		SAVE V2, V5
+	`)
+
+	a := NewAssembler()
+	a.ClearScreen()
+	a.SetRegister(1, 0x20)
+	a.Set(2, 1)
+	a.SetIndexRegister(0x300)
+	a.LongSetIndexRegister(0xABCD)
+	a.Store(3)
+	a.Load(4)
+	a.FontChar(5)
+	a.LargeFontChar(5)
+	a.BCD(6)
+	a.SetDelayTimer(7)
+	a.SetSoundTimer(7)
+	a.GetDelayTimer(8)
+	a.GetKey(9)
+	a.StoreFlags(2)
+	a.LoadFlags(2)
+	a.AddToRegister(1, 1)
+	a.Add(1, 2)
+	a.AddToIndex(3)
+	a.SkipIfEqual(1, 2)
+	a.SkipIfRegistersNotEqual(1, 2)
+	a.ShiftRight(1, 1)
+	a.ShiftLeft(0xA, 0xB)
+	a.Display(0, 1, 5)
+	a.SetJumpWithOffset(0x400)
+	a.Random(0xF, 0xFF)
+	a.SelectPlanes(3)
+	a.SaveRange(2, 5)
+	expected, err := a.Assemble()
+	suite.NoError(err)
+	suite.Equal(expected, code)
+}
+
+func (suite *SourceAssemblerTestSuite) TestLabelsAndForwardReferences() {
+	code := suite.assemble(`
+	start:	CALL draw      ; forward reference
+		JP start
+	draw:	LD I, sprite
+		RET
+	sprite:	db 0xF0
+	`)
+
+	suite.Equal([]byte{0x22, 0x04, 0x12, 0x00, 0xA2, 0x08, 0x00, 0xEE, 0xF0}, code)
+}
+
+func (suite *SourceAssemblerTestSuite) TestDataDirectives() {
+	code := suite.assemble(`
+		db 1, 0b10, -1, "AB"
+		dw 0x1234, end
+	end:
+	`)
+
+	suite.Equal([]byte{0x01, 0x02, 0xFF, 'A', 'B', 0x12, 0x34, 0x02, 0x09}, code)
+}
+
+func (suite *SourceAssemblerTestSuite) TestOrgFillsTheGap() {
+	code := suite.assemble(`
+		JP main
+		org 0x206
+	main:	EXIT
+	`)
+
+	suite.Equal([]byte{0x12, 0x06, 0x00, 0x00, 0x00, 0x00, 0x00, 0xFD}, code)
+}
+
+func (suite *SourceAssemblerTestSuite) TestOrgBeforeAnyCodePadsFromProgramStart() {
+	code := suite.assemble(`
+		org 0x204
+	loop:	JP loop
+	`)
+
+	suite.Equal([]byte{0x00, 0x00, 0x00, 0x00, 0x12, 0x04}, code)
+
+	err := suite.assemblyError("org 0x100\nJP 0x100")
+	suite.Equal("1:5: org 0x100 is before the current address 0x200", err.Error())
+}
+
+func (suite *SourceAssemblerTestSuite) TestConstantsAndExpressions() {
+	code := suite.assemble(`
+	WIDTH = HALF * 2
+	HALF equ 32
+		LD V0, WIDTH - 1
+		LD V1, (1 + 2) * 3
+		LD V2, 1 + 2 * 3
+		LD V3, 1 << 4 | 0x0F & 3
+		LD V4, ~0 & 0xFF
+		LD I, table + 2
+	table:
+	`)
+
+	suite.Equal([]byte{0x60, 63, 0x61, 9, 0x62, 7, 0x63, 0x13, 0x64, 0xFF, 0xA2, 0x0E}, code)
+}
+
+func (suite *SourceAssemblerTestSuite) TestComparisonsAndLogic() {
+	code := suite.assemble(`
+		LD V0, 1 + 2 == 3
+		LD V1, 3 < 2 || 2 >= 2 && 1 != 1
+		LD V2, !0 + (5 <= 4)
+	`)
+
+	suite.Equal([]byte{0x60, 1, 0x61, 0, 0x62, 1}, code)
+}
+
+func (suite *SourceAssemblerTestSuite) TestErrorsGiveLineAndColumn() {
+	err := suite.assemblyError("CLS\n  FOO V1\n")
+	suite.Equal(2, err.Line)
+	suite.Equal(3, err.Column)
+	suite.Equal("2:3: unknown instruction FOO", err.Error())
+
+	err = suite.assemblyError("JP nowhere")
+	suite.Equal("1:4: undefined symbol nowhere", err.Error())
+
+	err = suite.assemblyError("loop: CLS\nloop: CLS")
+	suite.Equal("2:1: loop is already defined at line 1", err.Error())
+
+	err = suite.assemblyError("LD V1, 256")
+	suite.Equal("1:8: value 256 is out of range for nn", err.Error())
+
+	err = suite.assemblyError("LD V1, DT, 3")
+	suite.Equal("1:1: invalid operands for LD", err.Error())
+
+	err = suite.assemblyError("org 0x300\norg 0x200")
+	suite.Equal("2:5: org 0x200 is before the current address 0x300", err.Error())
+
+	err = suite.assemblyError("X = Y\nY = X\nJP X")
+	suite.Equal("1:1: X is defined in terms of itself", err.Error())
+
+	err = suite.assemblyError("LD V1, (2")
+	suite.Equal("1:10: expected \")\" but found end of line", err.Error())
+}
+
+func (suite *SourceAssemblerTestSuite) TestErrorsIncludeTheFileName() {
+	_, err := AssembleSourceFile("game.asm", strings.NewReader("\n\tLD V1, 3 / 0"))
+	suite.EqualError(err, "game.asm:2:13: division by zero")
+}
+
+func (suite *SourceAssemblerTestSuite) TestAssembledProgramRuns() {
+	code := suite.assemble(`
+		LD V0, 0
+	loop:	ADD V0, 1
+		SE V0, 10
+		JP loop
+		EXIT
+	`)
+	display := mockDisplay{eventType: KeyboardEvent}
+	vm := NewVM(&display, MockRandom{55}, QuirksSuperChip)
+	vm.SetClock(NewVirtualClock())
+	vm.Load(code)
+
+	suite.NoError(vm.Run())
+
+	suite.Equal(byte(10), vm.registers[0])
+}
+
+func TestSourceAssemblerSuite(t *testing.T) {
+	suite.Run(t, new(SourceAssemblerTestSuite))
+}
@@ -23,10 +23,10 @@ func (s *stack) Push(value uint16) error {
 }
 
 func (s *stack) Pop() (uint16, error) {
-	s.index--
-	if s.index < 0 {
+	if s.index <= 0 {
 		return 0, errors.New("stack empty")
 	}
+	s.index--
 	value := s.address[s.index]
 	return value, nil
 }
@@ -75,6 +75,16 @@ func (suite *StackTestSuite) TestPopEmptyStack() {
 	suite.Equal(errors.New("stack empty"), err)
 }
 
+func (suite *StackTestSuite) TestPopEmptyStackLeavesStackUsable() {
+	theStack := stack{}
+	theStack.Pop()
+	theStack.Push(uint16(0x1111))
+	result, err := theStack.Pop()
+	suite.Nil(err)
+	suite.Equal(uint16(0x1111), result)
+	suite.Equal(0, theStack.length())
+}
+
 func TestStackTestSuite(t *testing.T) {
 	suite.Run(t, new(StackTestSuite))
 }
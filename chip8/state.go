@@ -0,0 +1,83 @@
+package chip8
+
+// State is a snapshot of the VM. It is a copy, so it doesn't change as the VM runs and changing it
+// doesn't affect the VM.
+type State struct {
+	Registers     [16]byte
+	IndexRegister uint16
+	PC            uint16
+	// SP is the number of return addresses on the stack.
+	SP int
+	// Stack holds the return addresses, the most recent call last.
+	Stack      []uint16
+	DelayTimer byte
+	SoundTimer byte
+	Quirks     Quirks
+	Display    DisplayState
+	// WaitingForKey is set while FX0A waits for a key to be released, which will go in VX for the
+	// KeyRegister X.
+	WaitingForKey bool
+	KeyRegister   byte
+}
+
+// DisplayState is a snapshot of the screen.
+type DisplayState struct {
+	Pixels         [][]byte
+	HighResolution bool
+	Planes         byte
+}
+
+// State returns a snapshot of the registers, stack, timers, quirks, screen and any wait for a key.
+func (v *VM) State() State {
+	return State{
+		Registers:     v.registers,
+		IndexRegister: v.indexRegister,
+		PC:            v.pc,
+		SP:            v.theStack.length(),
+		Stack:         append([]uint16(nil), v.theStack.address[:v.theStack.length()]...),
+		DelayTimer:    v.timers.Delay(),
+		SoundTimer:    v.timers.Sound(),
+		Quirks:        v.quirks,
+		Display:       v.displayBuffer.state(),
+		WaitingForKey: v.waitingForKey,
+		KeyRegister:   v.keyRegister,
+	}
+}
+
+func (d *DisplayBuffer) state() DisplayState {
+	pixels := make([][]byte, len(d.Pixels))
+	for y, row := range d.Pixels {
+		pixels[y] = append([]byte(nil), row...)
+	}
+	return DisplayState{
+		Pixels:         pixels,
+		HighResolution: d.highResolution,
+		Planes:         d.planes,
+	}
+}
+
+func (v *VM) PC() uint16 {
+	return v.pc
+}
+
+// SetPC moves execution to address, for debuggers.
+func (v *VM) SetPC(address uint16) {
+	v.pc = address
+}
+
+func (v *VM) SetRegister(x byte, value byte) {
+	v.registers[x&0xF] = value
+}
+
+func (v *VM) SetIndexRegister(address uint16) {
+	v.indexRegister = address
+}
+
+func (v *VM) SetDelayTimer(value byte) {
+	v.timers.setDelay(value)
+}
+
+// SetSoundTimer sets the sound timer, turning the buzzer on or off at the end of the frame.
+func (v *VM) SetSoundTimer(value byte) {
+	v.timers.setSound(value)
+}
@@ -0,0 +1,108 @@
+package chip8
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type StateTestSuite struct {
+	suite.Suite
+	vm *VM
+}
+
+func (suite *StateTestSuite) SetupTest() {
+	suite.vm = NewVM(&mockDisplay{eventType: KeyboardEvent}, MockRandom{55}, QuirksCosmacVIP)
+	suite.vm.SetClock(NewVirtualClock())
+}
+
+func (suite *StateTestSuite) load(a *Assembler) {
+	code, err := a.Assemble()
+	suite.Require().NoError(err)
+	suite.vm.Load(code)
+}
+
+func (suite *StateTestSuite) TestSnapshotOfRegistersAndStack() {
+	a := NewAssembler()
+	a.SetRegister(3, 0x10)
+	a.SetIndexRegister(0x300)
+	a.SetRegister(4, 5)
+	a.SetDelayTimer(3)
+	a.SetSoundTimer(4)
+	a.CallLabel("sub")
+	a.Label("sub")
+	a.Exit()
+	suite.load(a)
+
+	suite.vm.RunCycles(6)
+	state := suite.vm.State()
+
+	suite.Equal(byte(0x10), state.Registers[3])
+	suite.Equal(uint16(0x300), state.IndexRegister)
+	suite.Equal(uint16(0x20C), state.PC)
+	suite.Equal(1, state.SP)
+	suite.Equal([]uint16{0x20C}, state.Stack)
+	suite.Equal(byte(0x10), state.DelayTimer)
+	suite.Equal(byte(5), state.SoundTimer)
+	suite.Equal(QuirksCosmacVIP, state.Quirks)
+	suite.Equal(64, len(state.Display.Pixels[0]))
+	suite.Equal(byte(1), state.Display.Planes)
+}
+
+func (suite *StateTestSuite) TestSnapshotDoesNotChangeAsTheVMRuns() {
+	a := NewAssembler()
+	a.SetRegister(0, 1)
+	a.CallLabel("sub")
+	a.Label("sub")
+	a.SetIndexRegister(FontMemory)
+	a.Display(0, 0, 5)
+	a.Exit()
+	suite.load(a)
+	before := suite.vm.State()
+
+	suite.vm.RunCycles(4)
+
+	suite.Equal(byte(0), before.Registers[0])
+	suite.Equal(uint16(0x200), before.PC)
+	suite.Empty(before.Stack)
+	suite.Equal(byte(0), before.Display.Pixels[1][1])
+	suite.Equal(byte(1), suite.vm.DisplayBuffer().Pixels[1][1])
+}
+
+func (suite *StateTestSuite) TestChangingSnapshotDoesNotChangeTheVM() {
+	a := NewAssembler()
+	a.CallLabel("sub")
+	a.Label("sub")
+	a.Exit()
+	suite.load(a)
+	suite.vm.Step()
+
+	state := suite.vm.State()
+	state.Registers[0] = 0xFF
+	state.Stack[0] = 0x400
+	state.Display.Pixels[0][0] = 1
+
+	after := suite.vm.State()
+	suite.Equal(byte(0), after.Registers[0])
+	suite.Equal([]uint16{0x202}, after.Stack)
+	suite.Equal(byte(0), suite.vm.DisplayBuffer().Pixels[0][0])
+}
+
+func (suite *StateTestSuite) TestSetters() {
+	suite.vm.SetRegister(0x1A, 0x42)
+	suite.vm.SetIndexRegister(0x345)
+	suite.vm.SetPC(0x210)
+	suite.vm.SetDelayTimer(9)
+	suite.vm.SetSoundTimer(8)
+
+	state := suite.vm.State()
+	suite.Equal(byte(0x42), state.Registers[0xA])
+	suite.Equal(uint16(0x345), state.IndexRegister)
+	suite.Equal(uint16(0x210), state.PC)
+	suite.Equal(byte(9), state.DelayTimer)
+	suite.Equal(byte(8), state.SoundTimer)
+	suite.True(suite.vm.BuzzerOn())
+}
+
+func TestStateSuite(t *testing.T) {
+	suite.Run(t, new(StateTestSuite))
+}
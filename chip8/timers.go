@@ -0,0 +1,55 @@
+package chip8
+
+import "sync"
+
+// Timers holds the delay and sound timers. They are owned by the VM and count down once per frame,
+// but can be read from other goroutines, e.g. an audio callback checking the buzzer.
+type Timers struct {
+	mutex sync.Mutex
+	delay byte
+	sound byte
+}
+
+func NewTimers() *Timers {
+	return new(Timers)
+}
+
+func (t *Timers) tick() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.delay > 0 {
+		t.delay--
+	}
+	if t.sound > 0 {
+		t.sound--
+	}
+}
+
+func (t *Timers) Delay() byte {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.delay
+}
+
+func (t *Timers) setDelay(b byte) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.delay = b
+}
+
+func (t *Timers) Sound() byte {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.sound
+}
+
+func (t *Timers) setSound(b byte) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.sound = b
+}
+
+// BuzzerOn reports whether the sound timer is running.
+func (t *Timers) BuzzerOn() bool {
+	return t.Sound() > 0
+}
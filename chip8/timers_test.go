@@ -0,0 +1,52 @@
+package chip8
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type TimersTestSuite struct {
+	suite.Suite
+}
+
+func (suite *TimersTestSuite) TestTimersStartAtZero() {
+	timers := NewTimers()
+	suite.Equal(byte(0), timers.Delay())
+	suite.Equal(byte(0), timers.Sound())
+	suite.False(timers.BuzzerOn())
+}
+
+func (suite *TimersTestSuite) TestTickDecrementsBothTimers() {
+	timers := NewTimers()
+	timers.setDelay(5)
+	timers.setSound(3)
+
+	timers.tick()
+
+	suite.Equal(byte(4), timers.Delay())
+	suite.Equal(byte(2), timers.Sound())
+}
+
+func (suite *TimersTestSuite) TestTimersStopAtZero() {
+	timers := NewTimers()
+	timers.setDelay(1)
+
+	timers.tick()
+	timers.tick()
+
+	suite.Equal(byte(0), timers.Delay())
+	suite.Equal(byte(0), timers.Sound())
+}
+
+func (suite *TimersTestSuite) TestBuzzerOnWhileSoundTimerRuns() {
+	timers := NewTimers()
+	timers.setSound(1)
+	suite.True(timers.BuzzerOn())
+
+	timers.tick()
+	suite.False(timers.BuzzerOn())
+}
+
+func TestTimersTestSuite(t *testing.T) {
+	suite.Run(t, new(TimersTestSuite))
+}
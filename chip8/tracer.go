@@ -0,0 +1,131 @@
+package chip8
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// TraceEvent describes a single executed instruction.
+type TraceEvent struct {
+	PC     uint16 `json:"pc"`
+	NextPC uint16 `json:"nextPC"`
+	Opcode uint16 `json:"opcode"`
+	// Mnemonic is the instruction as the disassembler writes it, e.g. "ADD V1, 0x05".
+	Mnemonic        string   `json:"mnemonic"`
+	RegistersBefore [16]byte `json:"registersBefore"`
+	RegistersAfter  [16]byte `json:"registersAfter"`
+	IndexRegister   uint16   `json:"i"`
+	DelayTimer      byte     `json:"delayTimer"`
+	SoundTimer      byte     `json:"soundTimer"`
+}
+
+// Tracer receives an event for every instruction the VM executes.
+type Tracer interface {
+	Trace(event TraceEvent)
+}
+
+// NullTracer discards every event. The VM doesn't build events at all when it is given one.
+type NullTracer struct{}
+
+func (n NullTracer) Trace(event TraceEvent) {
+}
+
+// TraceLevel decides which instructions are traced.
+type TraceLevel int
+
+const (
+	// TraceAll traces every instruction.
+	TraceAll TraceLevel = iota
+	// TraceFlow traces only the instructions that go somewhere other than the next one: jumps, calls,
+	// returns and skips that skipped.
+	TraceFlow
+)
+
+// TraceLevelByName returns the level for "all" or "flow".
+func TraceLevelByName(name string) (TraceLevel, bool) {
+	switch name {
+	case "all":
+		return TraceAll, true
+	case "flow":
+		return TraceFlow, true
+	}
+	return 0, false
+}
+
+// LevelTracer passes on the events at its level to another tracer.
+type LevelTracer struct {
+	tracer Tracer
+	level  TraceLevel
+}
+
+func NewLevelTracer(tracer Tracer, level TraceLevel) *LevelTracer {
+	return &LevelTracer{tracer, level}
+}
+
+func (t *LevelTracer) Trace(event TraceEvent) {
+	if t.level == TraceFlow && !event.changesFlow() {
+		return
+	}
+	t.tracer.Trace(event)
+}
+
+func (e TraceEvent) changesFlow() bool {
+	size := uint16(2)
+	if e.Opcode == 0xF000 {
+		size = 4
+	}
+	return e.NextPC != e.PC+size
+}
+
+// TextTracer writes one human-readable line per instruction.
+type TextTracer struct {
+	writer io.Writer
+}
+
+func NewTextTracer(writer io.Writer) *TextTracer {
+	return &TextTracer{writer}
+}
+
+func (t *TextTracer) Trace(event TraceEvent) {
+	fmt.Fprintf(t.writer, "%03X  %04X  %-24s", event.PC, event.Opcode, event.Mnemonic)
+	for n, value := range event.RegistersAfter {
+		if value != event.RegistersBefore[n] {
+			fmt.Fprintf(t.writer, " V%X=%02X->%02X", n, event.RegistersBefore[n], value)
+		}
+	}
+	fmt.Fprintf(t.writer, "  I=%03X DT=%02X ST=%02X\n", event.IndexRegister, event.DelayTimer, event.SoundTimer)
+}
+
+// JSONTracer writes one JSON object per instruction, in JSON Lines format.
+type JSONTracer struct {
+	encoder *json.Encoder
+}
+
+func NewJSONTracer(writer io.Writer) *JSONTracer {
+	return &JSONTracer{json.NewEncoder(writer)}
+}
+
+func (t *JSONTracer) Trace(event TraceEvent) {
+	t.encoder.Encode(event)
+}
+
+// TracerByName returns a NullTracer for "off", or the tracer for "text" or "json" writing the
+// instructions at level to writer.
+func TracerByName(name string, level TraceLevel, writer io.Writer) (Tracer, bool) {
+	var tracer Tracer
+	switch name {
+	case "off":
+		return NullTracer{}, true
+	case "text":
+		tracer = NewTextTracer(writer)
+	case "json":
+		tracer = NewJSONTracer(writer)
+	default:
+		return nil, false
+	}
+	if level != TraceAll {
+		tracer = NewLevelTracer(tracer, level)
+	}
+	return tracer, true
+}
@@ -0,0 +1,99 @@
+package chip8
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type TracerTestSuite struct {
+	suite.Suite
+}
+
+func traceEvent() TraceEvent {
+	event := TraceEvent{
+		PC:            0x202,
+		NextPC:        0x204,
+		Opcode:        0x7105,
+		Mnemonic:      "ADD V1, 0x05",
+		IndexRegister: 0x300,
+		DelayTimer:    0x10,
+	}
+	event.RegistersBefore[1] = 0x01
+	event.RegistersAfter[1] = 0x06
+	return event
+}
+
+func (suite *TracerTestSuite) TestTextTracer() {
+	var output bytes.Buffer
+	NewTextTracer(&output).Trace(traceEvent())
+
+	suite.Equal("202  7105  ADD V1, 0x05             V1=01->06  I=300 DT=10 ST=00\n", output.String())
+}
+
+func (suite *TracerTestSuite) TestJSONTracer() {
+	var output bytes.Buffer
+	tracer := NewJSONTracer(&output)
+	tracer.Trace(traceEvent())
+	tracer.Trace(traceEvent())
+
+	line := `{"pc":514,"nextPC":516,"opcode":28933,"mnemonic":"ADD V1, 0x05",` +
+		`"registersBefore":[0,1,0,0,0,0,0,0,0,0,0,0,0,0,0,0],` +
+		`"registersAfter":[0,6,0,0,0,0,0,0,0,0,0,0,0,0,0,0],` +
+		`"i":768,"delayTimer":16,"soundTimer":0}` + "\n"
+	suite.Equal(line+line, output.String())
+}
+
+func (suite *TracerTestSuite) TestTracerByName() {
+	_, ok := TracerByName("text", TraceAll, &bytes.Buffer{})
+	suite.True(ok)
+	tracer, ok := TracerByName("off", TraceFlow, &bytes.Buffer{})
+	suite.True(ok)
+	suite.Equal(NullTracer{}, tracer)
+	_, ok = TracerByName("xml", TraceAll, &bytes.Buffer{})
+	suite.False(ok)
+}
+
+func (suite *TracerTestSuite) TestNullTracerTurnsTracingOff() {
+	vm := NewVM(&mockDisplay{}, MockRandom{}, QuirksCosmacVIP)
+	vm.SetTracer(NullTracer{})
+
+	suite.Nil(vm.tracer)
+}
+
+func (suite *TracerTestSuite) TestFlowLevelOnlyTracesJumpsAndSkips() {
+	tracer := &mockTracer{}
+	vm := NewVM(&mockDisplay{}, MockRandom{}, QuirksCosmacVIP)
+	vm.SetTracer(NewLevelTracer(tracer, TraceFlow))
+	// LD V1, 1; SE V1, 1; LD V2, 2; SE V1, 2; JP 0x200
+	vm.Load([]byte{0x61, 0x01, 0x31, 0x01, 0x62, 0x02, 0x31, 0x02, 0x12, 0x00})
+
+	vm.RunCycles(4)
+
+	suite.Equal(2, len(tracer.events))
+	suite.Equal("SE V1, 0x01", tracer.events[0].Mnemonic)
+	suite.Equal("JP 0x200", tracer.events[1].Mnemonic)
+}
+
+func (suite *TracerTestSuite) TestVMTracesEachInstruction() {
+	tracer := &mockTracer{}
+	vm := NewVM(&mockDisplay{}, MockRandom{}, QuirksCosmacVIP)
+	vm.SetTracer(tracer)
+	vm.Load([]byte{0x61, 0x01, 0x71, 0x05, 0xA3, 0x00})
+
+	vm.RunCycles(3)
+
+	suite.Equal(3, len(tracer.events))
+	event := tracer.events[1]
+	suite.Equal(uint16(0x202), event.PC)
+	suite.Equal(uint16(0x7105), event.Opcode)
+	suite.Equal("ADD V1, 0x05", event.Mnemonic)
+	suite.Equal(uint16(0x204), event.NextPC)
+	suite.Equal(byte(0x01), event.RegistersBefore[1])
+	suite.Equal(byte(0x06), event.RegistersAfter[1])
+	suite.Equal(uint16(0x300), tracer.events[2].IndexRegister)
+}
+
+func TestTracerTestSuite(t *testing.T) {
+	suite.Run(t, new(TracerTestSuite))
+}
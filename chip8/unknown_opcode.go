@@ -0,0 +1,24 @@
+package chip8
+
+// UnknownOpcodePolicy decides what the VM does when it fetches an opcode it doesn't implement.
+type UnknownOpcodePolicy int
+
+const (
+	// HaltOnUnknownOpcode stops the VM with an UnknownOpcode *VMError.
+	HaltOnUnknownOpcode UnknownOpcodePolicy = iota
+	// SkipUnknownOpcode treats the opcode as a NOP.
+	SkipUnknownOpcode
+	// TrapUnknownOpcode passes the opcode to the handler set with SetTrapHandler.
+	TrapUnknownOpcode
+)
+
+// TrapHandler is called with an unknown opcode after the PC has moved past it. Returning an error halts the VM.
+type TrapHandler func(vm *VM, opcode uint16) error
+
+func (v *VM) SetUnknownOpcodePolicy(policy UnknownOpcodePolicy) {
+	v.unknownOpcodePolicy = policy
+}
+
+func (v *VM) SetTrapHandler(handler TrapHandler) {
+	v.trapHandler = handler
+}
@@ -1,72 +1,375 @@
 package chip8
 
+import (
+	"crypto/sha256"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// errExit is returned by the SUPER-CHIP exit instruction to halt the VM
+var errExit = errors.New("exit")
+
+const frameDuration = time.Microsecond * 16667
+
+const DefaultInstructionsPerFrame = 11
+
+const memorySize = 0x1000
+const extendedMemorySize = 0x10000
+
+type StepResult int
+
+const (
+	// StepExecuted means an instruction was executed.
+	StepExecuted StepResult = iota
+	// StepHalted means a 0x0000 word or 00FD was fetched and the program has finished.
+	StepHalted
+	// StepWaiting means no instruction was executed because the VM is waiting for a key or the vertical blank.
+	StepWaiting
+	// StepPaused means no instruction was executed because the VM is paused.
+	StepPaused
+	// StepBreakpoint means a breakpoint fired, described by BreakpointHit.
+	StepBreakpoint
+)
+
 type VM struct {
-	Memory              [4096]byte
-	registers           [16]byte
-	indexRegister       uint16
-	pc                  uint16
-	pcIncrementer       int
-	display             DisplayInterface
-	processInstructions bool
-	xCoord              byte
-	yCoord              byte
-	random              Random
-	theStack            *stack
-	delayTimer          *DelayTimer
-}
-
-func NewVM(display DisplayInterface, random Random) *VM {
+	Memory               []byte
+	registers            [16]byte
+	indexRegister        uint16
+	pc                   uint16
+	display              DisplayInterface
+	displayBuffer        *DisplayBuffer
+	audio                AudioInterface
+	soundPattern         SoundPattern
+	toneOn               bool
+	keypad               Keypad
+	xCoord               byte
+	yCoord               byte
+	random               Random
+	theStack             *stack
+	timers               *Timers
+	quirks               Quirks
+	waitingForVBlank     bool
+	waitingForKey        bool
+	keyRegister          byte
+	paused               int32
+	stopped              int32
+	instructionsPerFrame int
+	clock                Clock
+	unknownOpcodePolicy  UnknownOpcodePolicy
+	trapHandler          TrapHandler
+	tracer               Tracer
+	rplFlags             [16]byte
+	breakpoints          []*Breakpoint
+	nextBreakpointID     int
+	breakpointHit        *BreakpointHit
+	resuming             bool
+	resumeAt             uint16
+	accesses             []memoryAccess
+	romHash              [sha256.Size]byte
+	rewind               *Rewind
+}
+
+func NewVM(display DisplayInterface, random Random, quirks Quirks) *VM {
 	vm := new(VM)
 	vm.display = display
+	vm.audio = NullAudio{}
+	vm.soundPattern = DefaultSoundPattern
 	vm.random = random
+	vm.quirks = quirks
+	if quirks.ExtendedMemory {
+		vm.Memory = make([]byte, extendedMemorySize)
+	} else {
+		vm.Memory = make([]byte, memorySize)
+	}
+	vm.displayBuffer = NewDisplayBuffer()
+	vm.displayBuffer.SetSpriteWrapping(quirks.WrapSprites)
 	vm.pc = 0x200
-	vm.pcIncrementer = 2
 	vm.theStack = new(stack)
-	vm.processInstructions = true
-	font := createFont()
-	copy(vm.Memory[0x50:], font)
-	vm.delayTimer = NewDelayTimer()
+	copy(vm.Memory[fontMemory:], createFont())
+	copy(vm.Memory[largeFontMemory:], createLargeFont())
+	vm.timers = NewTimers()
+	vm.instructionsPerFrame = DefaultInstructionsPerFrame
+	vm.clock = NewRealTimeClock()
 	return vm
 }
 
 func (v *VM) Load(bytes []byte) {
 	copy(v.Memory[0x200:], bytes)
+	v.romHash = sha256.Sum256(bytes)
+}
+
+// Keypad returns the keys the program sees, which the display presses and releases every frame.
+func (v *VM) Keypad() *Keypad {
+	return &v.keypad
+}
+
+// DisplayBuffer returns the screen the VM draws on.
+func (v *VM) DisplayBuffer() *DisplayBuffer {
+	return v.displayBuffer
 }
 
-func (v *VM) Run() {
-	v.delayTimer.Start()
-	for {
-		if v.processInstructions == true {
-			quit := v.fetchAndProcessInstruction()
-			if quit == true {
-				return
+func (v *VM) SetInstructionsPerFrame(n int) {
+	v.instructionsPerFrame = n
+}
+
+func (v *VM) InstructionsPerFrame() int {
+	return v.instructionsPerFrame
+}
+
+func (v *VM) SetAudio(audio AudioInterface) {
+	v.audio = audio
+	v.audio.SetPattern(v.soundPattern)
+}
+
+// SetTracer sets the tracer that receives an event for every executed instruction. A NullTracer or
+// nil stops tracing.
+func (v *VM) SetTracer(tracer Tracer) {
+	if _, off := tracer.(NullTracer); off {
+		tracer = nil
+	}
+	v.tracer = tracer
+}
+
+// SetRewind makes Run record every frame in r, and step back through them while r is rewinding.
+func (v *VM) SetRewind(r *Rewind) {
+	v.rewind = r
+}
+
+func (v *VM) SetClock(clock Clock) {
+	v.clock = clock
+}
+
+// Run executes frames until the program halts, the display is closed or Stop is called.
+// If the program faults, the *VMError is returned, and if a breakpoint fires, the *BreakpointHit.
+func (v *VM) Run() error {
+	defer atomic.StoreInt32(&v.stopped, 0)
+	for atomic.LoadInt32(&v.stopped) == 0 {
+		if v.rewind != nil && v.rewind.Rewinding() {
+			if _, err := v.rewind.StepBack(); err != nil {
+				return err
+			}
+		} else if !v.Paused() {
+			result, err := v.RunFrame()
+			if err != nil {
+				v.refreshDisplay()
+				return err
 			}
+			if result == StepHalted {
+				v.refreshDisplay()
+				return nil
+			}
+			if result == StepBreakpoint {
+				v.refreshDisplay()
+				return v.breakpointHit
+			}
+			if v.rewind != nil {
+				if err := v.rewind.Record(); err != nil {
+					return err
+				}
+			}
+		}
+
+		if !v.PresentFrame() {
+			return nil
+		}
+	}
+	return nil
+}
+
+// PresentFrame renders the display if it has changed, handles window events and waits for the next
+// frame. It returns false once the display has been closed.
+func (v *VM) PresentFrame() bool {
+	v.refreshDisplay()
+	v.keypad.clearReleased()
+	if v.display.PollEvents(&v.keypad) == QuitEvent {
+		return false
+	}
+	v.clock.WaitForFrame()
+	return true
+}
+
+func (v *VM) refreshDisplay() {
+	if v.displayBuffer.dirty {
+		v.displayBuffer.dirty = false
+		v.display.Render(v.displayBuffer)
+	}
+}
+
+// RunFrame executes one 60 Hz frame: up to the configured number of instructions, then a timer tick.
+func (v *VM) RunFrame() (StepResult, error) {
+	result, err := v.RunCycles(v.instructionsPerFrame)
+	if result == StepHalted || result == StepPaused || result == StepBreakpoint || err != nil {
+		return result, err
+	}
+	v.TickFrame()
+	return result, nil
+}
+
+// TickFrame marks the end of a frame, decrementing the timers and ending any wait for the vertical blank.
+func (v *VM) TickFrame() {
+	v.timers.tick()
+	v.updateTone()
+	v.audio.EndFrame()
+	v.waitingForVBlank = false
+}
+
+func (v *VM) updateTone() {
+	buzzerOn := v.timers.BuzzerOn()
+	if buzzerOn != v.toneOn {
+		v.toneOn = buzzerOn
+		v.audio.SetTone(buzzerOn)
+	}
+}
+
+// Step executes exactly one instruction, even when the VM is paused. If the instruction faults, the
+// PC is left pointing at it and a *VMError is returned.
+func (v *VM) Step() (StepResult, error) {
+	v.breakpointHit = nil
+	if v.waitingForKey && !v.takeKey() {
+		return StepWaiting, nil
+	}
+	if v.waitingForVBlank {
+		return StepWaiting, nil
+	}
+	if len(v.breakpoints) > 0 && v.checkPCBreakpoints() {
+		return StepBreakpoint, nil
+	}
+	pc, registersBefore, indexBefore := v.pc, v.registers, v.indexRegister
+	quit, err := v.fetchAndProcessInstruction()
+	if err != nil {
+		v.accesses = v.accesses[:0]
+		return StepHalted, err
+	}
+	if quit == true {
+		return StepHalted, nil
+	}
+	if len(v.breakpoints) > 0 && v.checkBreakpoints(pc, registersBefore, indexBefore) {
+		return StepBreakpoint, nil
+	}
+	return StepExecuted, nil
+}
+
+// RunCycles executes up to n instructions, stopping early if the VM halts, waits or is paused.
+func (v *VM) RunCycles(n int) (StepResult, error) {
+	result := StepExecuted
+	for count := 0; count < n; count++ {
+		if v.Paused() {
+			return StepPaused, nil
+		}
+		var err error
+		result, err = v.Step()
+		if result != StepExecuted || err != nil {
+			return result, err
 		}
+	}
+	return result, nil
+}
 
-		eventType := v.display.PollEvents()
-		if eventType == KeyboardEvent {
-			v.processInstructions = true
+// RunFor executes instructions until d has elapsed, stopping early if the VM halts, waits or is paused.
+func (v *VM) RunFor(d time.Duration) (StepResult, error) {
+	result := StepExecuted
+	deadline := time.Now().Add(d)
+	for time.Now().Before(deadline) {
+		if v.Paused() {
+			return StepPaused, nil
 		}
-		if eventType == QuitEvent {
-			return
+		var err error
+		result, err = v.Step()
+		if result != StepExecuted || err != nil {
+			return result, err
 		}
 	}
+	return result, nil
 }
 
-func (v *VM) fetchAndProcessInstruction() (quit bool) {
+// Stop makes Run return at the end of the current frame. It is safe to call from another goroutine.
+func (v *VM) Stop() {
+	atomic.StoreInt32(&v.stopped, 1)
+}
+
+// BuzzerOn reports whether the sound timer is running. It is safe to call from another goroutine.
+func (v *VM) BuzzerOn() bool {
+	return v.timers.BuzzerOn()
+}
+
+// Pause stops Run, RunCycles and RunFor from executing instructions until Resume is called.
+// It is safe to call from another goroutine.
+func (v *VM) Pause() {
+	atomic.StoreInt32(&v.paused, 1)
+}
+
+func (v *VM) Resume() {
+	atomic.StoreInt32(&v.paused, 0)
+}
+
+func (v *VM) Paused() bool {
+	return atomic.LoadInt32(&v.paused) == 1
+}
+
+func (v *VM) fetchAndProcessInstruction() (quit bool, err error) {
+	pc := v.pc
+	if int(pc)+1 >= len(v.Memory) {
+		return true, v.newError(PCOutOfRange, pc, 0)
+	}
 	instr := v.fetchAndIncrement()
 	if instr == 0x0000 {
-		return true
+		return true, nil
+	}
+	registersBefore := v.registers
+	i := newInstruction(pc, instr, v)
+	op := decode(instr)
+	if op != nil {
+		err = op.function(i)
+	} else {
+		err = i.unknownOpcode()
+	}
+	if err == errExit {
+		v.pc = pc
+		return true, nil
+	}
+	if err != nil {
+		v.pc = pc
+		return true, err
 	}
-	v.pcIncrementer = 2
-	i := NewInstruction(instr, v)
-	i.execute()
-	return quit
+	if v.tracer != nil {
+		v.trace(pc, instr, registersBefore)
+	}
+	return false, nil
+}
+
+func (v *VM) trace(pc uint16, instr uint16, registersBefore [16]byte) {
+	mnemonic, _ := disassembleAt(v.Memory, int(pc))
+	v.tracer.Trace(TraceEvent{
+		PC:              pc,
+		NextPC:          v.pc,
+		Opcode:          instr,
+		Mnemonic:        mnemonic,
+		RegistersBefore: registersBefore,
+		RegistersAfter:  v.registers,
+		IndexRegister:   v.indexRegister,
+		DelayTimer:      v.timers.Delay(),
+		SoundTimer:      v.timers.Sound(),
+	})
+}
+
+// takeKey ends the wait for FX0A if a key has been released, putting the key in the register.
+func (v *VM) takeKey() bool {
+	key, ok := v.keypad.takeReleased()
+	if ok {
+		v.registers[v.keyRegister] = key
+		v.waitingForKey = false
+	}
+	return ok
+}
+
+func (v *VM) waitForVBlank() {
+	v.waitingForVBlank = true
 }
 
 func (v *VM) fetchAndIncrement() uint16 {
 	i := bytesToWord(v.Memory[v.pc], v.Memory[v.pc+1])
-	v.pc += uint16(v.pcIncrementer)
+	v.pc += 2
 	return i
 }
 
@@ -77,7 +380,3 @@ func (v *VM) getXCoordinate() byte {
 func (v *VM) getYCoordinate() byte {
 	return v.yCoord
 }
-
-func (v *VM) setDelayTimer(b byte) {
-	v.delayTimer.setTimer(b)
-}
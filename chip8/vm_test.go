@@ -1,8 +1,10 @@
 package chip8
 
 import (
+	"errors"
 	"github.com/stretchr/testify/suite"
 	"testing"
+	"time"
 )
 
 type Chip8TestSuite struct {
@@ -17,14 +19,21 @@ const FontMemory = 0x50
 const programStart = 0x200
 
 func (suite *Chip8TestSuite) SetupTest() {
-	suite.mockDisplay = mockDisplay{false, drawPatternValues{}, KeyboardEvent, 4}
+	suite.mockDisplay = mockDisplay{eventType: KeyboardEvent}
 	suite.mockRandom = MockRandom{55}
-	suite.vm = NewVM(&suite.mockDisplay, suite.mockRandom)
+	suite.vm = NewVM(&suite.mockDisplay, suite.mockRandom, QuirksCosmacVIP)
+	suite.vm.SetClock(NewVirtualClock())
 	suite.asm = NewAssembler()
 }
 
+func (suite *Chip8TestSuite) assembled() []byte {
+	code, err := suite.asm.Assemble()
+	suite.Require().NoError(err)
+	return code
+}
+
 func (suite *Chip8TestSuite) executeInstructions() {
-	suite.vm.Load(suite.asm.Assemble())
+	suite.vm.Load(suite.assembled())
 	suite.vm.Run()
 }
 
@@ -104,10 +113,12 @@ func (suite *Chip8TestSuite) TestSetJumpToAddress() {
 func (suite *Chip8TestSuite) TestClearScreen() {
 	suite.asm.ClearScreen()
 
-	suite.vm.Load(suite.asm.Assemble())
+	suite.vm.Load(suite.assembled())
+	suite.vm.displayBuffer.Pixels[5][5] = 1
 	suite.vm.Run()
 
-	suite.True(suite.mockDisplay.screenCleared)
+	suite.Equal(byte(0), suite.vm.displayBuffer.GetPixelAt(5, 5))
+	suite.Equal(1, suite.mockDisplay.renders)
 }
 
 func (suite *Chip8TestSuite) TestGetCoordinatesFromRegisters_whenDraw() {
@@ -115,7 +126,7 @@ func (suite *Chip8TestSuite) TestGetCoordinatesFromRegisters_whenDraw() {
 	suite.vm.registers[10] = 30
 
 	suite.asm.Display(5, 0xA, 0)
-	suite.vm.Load(suite.asm.Assemble())
+	suite.vm.Load(suite.assembled())
 
 	suite.vm.Run()
 
@@ -130,7 +141,7 @@ func (suite *Chip8TestSuite) TestCoordinatesShouldWrap() {
 
 	suite.asm.Display(5, 0xA, 5)
 
-	suite.vm.Load(suite.asm.Assemble())
+	suite.vm.Load(suite.assembled())
 
 	suite.vm.Run()
 
@@ -159,14 +170,15 @@ func (suite *Chip8TestSuite) TestDraw() {
 	suite.asm.SetIndexRegister(0x50)
 	suite.asm.Display(0x5, 0xA, 5)
 
-	suite.vm.Load(suite.asm.Assemble())
+	suite.vm.Load(suite.assembled())
 
 	suite.vm.Run()
 
-	suite.Equal(byte(20), suite.mockDisplay.values.x)
-	suite.Equal(byte(30), suite.mockDisplay.values.y)
-	suite.Equal(uint16(0x50), suite.mockDisplay.values.address)
-	suite.Equal(byte(5), suite.mockDisplay.values.numberOfBytes)
+	// Only the first two rows of the 0 character fit on the screen
+	screen := suite.vm.DisplayBuffer()
+	suite.Equal([]byte{0, 1, 1, 1, 1, 0}, screen.Pixels[30][19:25])
+	suite.Equal([]byte{0, 1, 0, 0, 1, 0}, screen.Pixels[31][19:25])
+	suite.Equal(1, suite.mockDisplay.renders)
 }
 
 func (suite *Chip8TestSuite) TestVXIsSetToVY() {
@@ -335,6 +347,57 @@ func (suite *Chip8TestSuite) TestVXShiftLeftWithOverflow() {
 	suite.Equal(byte(1), suite.vm.registers[15])
 }
 
+func (suite *Chip8TestSuite) TestVXShiftRightInPlace_whenShiftQuirkDisabled() {
+	suite.vm = NewVM(&suite.mockDisplay, suite.mockRandom, QuirksSuperChip)
+	suite.asm.SetRegister(0, 0b00000011)
+	suite.asm.SetRegister(1, 0b11110000)
+	suite.asm.ShiftRight(0, 1)
+
+	suite.executeInstructions()
+
+	suite.Equal(byte(0b00000001), suite.vm.registers[0])
+	suite.Equal(byte(1), suite.vm.registers[15])
+}
+
+func (suite *Chip8TestSuite) TestVXShiftLeftInPlace_whenShiftQuirkDisabled() {
+	suite.vm = NewVM(&suite.mockDisplay, suite.mockRandom, QuirksSuperChip)
+	suite.asm.SetRegister(0, 0b10000001)
+	suite.asm.SetRegister(1, 0b00001111)
+	suite.asm.ShiftLeft(0, 1)
+
+	suite.executeInstructions()
+
+	suite.Equal(byte(0b00000010), suite.vm.registers[0])
+	suite.Equal(byte(1), suite.vm.registers[15])
+}
+
+func (suite *Chip8TestSuite) TestLogicResetsVF() {
+	suite.asm.SetRegister(15, 1)
+	suite.asm.Or(0, 1)
+
+	suite.executeInstructions()
+
+	suite.Equal(byte(0), suite.vm.registers[15])
+}
+
+func (suite *Chip8TestSuite) TestLogicLeavesVF_whenResetQuirkDisabled() {
+	suite.vm = NewVM(&suite.mockDisplay, suite.mockRandom, QuirksSuperChip)
+	suite.asm.SetRegister(15, 1)
+	suite.asm.And(0, 1)
+
+	suite.executeInstructions()
+
+	suite.Equal(byte(1), suite.vm.registers[15])
+}
+
+func (suite *Chip8TestSuite) TestSpriteWrappingIsPassedToDisplay() {
+	suite.Equal(false, suite.vm.displayBuffer.wrap)
+
+	suite.vm = NewVM(&suite.mockDisplay, suite.mockRandom, QuirksXOChip)
+
+	suite.Equal(true, suite.vm.displayBuffer.wrap)
+}
+
 func (suite *Chip8TestSuite) TestIndexPointsToCharacter0() {
 	suite.asm.SetRegister(0, 0x0)
 	suite.asm.FontChar(0)
@@ -378,10 +441,10 @@ func (suite *Chip8TestSuite) TestRandomNumber() {
 }
 
 func (suite *Chip8TestSuite) verifyRandomIsStoredInRegister(instruction byte, bitmask byte, fakeRandom byte, expected int, expectedRegister int) {
-	m := mockDisplay{false, drawPatternValues{}, QuitEvent, 0}
+	m := mockDisplay{eventType: QuitEvent}
 	r := MockRandom{fakeRandom}
 
-	suite.vm = NewVM(&m, r)
+	suite.vm = NewVM(&m, r, QuirksCosmacVIP)
 
 	suite.vm.Load([]byte{
 		instruction, bitmask, // Random number into register 0, ANDed with 0xFF
@@ -529,7 +592,7 @@ func (suite *Chip8TestSuite) TestReturnFromSubroutine() {
 
 	suite.asm.Return()
 
-	suite.vm.Load(suite.asm.Assemble())
+	suite.vm.Load(suite.assembled())
 	suite.vm.Run()
 	suite.Equal(uint16(0xA14), suite.vm.pc)
 }
@@ -545,7 +608,7 @@ func (suite *Chip8TestSuite) TestJumpAndReturnFromSubroutine() {
 	suite.asm.SetRegister(5, 0xAA) // subroutine
 	suite.asm.Return()
 
-	suite.vm.Load(suite.asm.Assemble())
+	suite.vm.Load(suite.assembled())
 	suite.vm.Run()
 	suite.Equal(byte(0x11), suite.vm.registers[0])
 	suite.Equal(byte(0x22), suite.vm.registers[1])
@@ -555,15 +618,55 @@ func (suite *Chip8TestSuite) TestJumpAndReturnFromSubroutine() {
 }
 
 func (suite *Chip8TestSuite) TestGetKey() {
-	suite.mockDisplay = mockDisplay{false, drawPatternValues{}, KeyboardEvent, 55}
-	suite.mockRandom = MockRandom{55}
-	suite.mockDisplay.SetKey(55)
-	suite.vm = NewVM(&suite.mockDisplay, suite.mockRandom)
+	suite.asm.GetKey(3)
+	suite.asm.AddToRegister(1, 1)
+	suite.vm.Load(suite.assembled())
+
+	result, _ := suite.vm.RunCycles(10)
+
+	suite.Equal(StepWaiting, result)
+	suite.Equal(uint16(0x202), suite.vm.pc)
+	suite.True(suite.vm.State().WaitingForKey)
+	suite.Equal(byte(3), suite.vm.State().KeyRegister)
+
+	suite.vm.Keypad().Press(0xB)
+	result, _ = suite.vm.Step()
+	suite.Equal(StepWaiting, result)
 
+	suite.vm.Keypad().Release(0xB)
+	result, _ = suite.vm.Step()
+
+	suite.Equal(StepExecuted, result)
+	suite.False(suite.vm.State().WaitingForKey)
+	suite.Equal(byte(0xB), suite.vm.registers[3])
+	suite.Equal(byte(1), suite.vm.registers[1])
+}
+
+func (suite *Chip8TestSuite) TestGetKeyIgnoresKeysReleasedBeforeIt() {
 	suite.asm.GetKey(3)
-	suite.vm.Load(suite.asm.Assemble())
-	suite.vm.Run()
-	suite.Equal(byte(55), suite.vm.registers[3])
+	suite.vm.Load(suite.assembled())
+	suite.vm.Keypad().Press(0xB)
+	suite.vm.Keypad().Release(0xB)
+
+	suite.vm.Step()
+	result, _ := suite.vm.Step()
+
+	suite.Equal(StepWaiting, result)
+	suite.Equal(byte(0), suite.vm.registers[3])
+}
+
+func (suite *Chip8TestSuite) TestTimersRunWhileWaitingForKey() {
+	suite.asm.SetRegister(0, 10)
+	suite.asm.SetDelayTimer(0)
+	suite.asm.GetKey(3)
+	suite.vm.Load(suite.assembled())
+
+	for frame := 0; frame < 4; frame++ {
+		suite.vm.RunFrame()
+	}
+
+	suite.True(suite.vm.State().WaitingForKey)
+	suite.Equal(byte(6), suite.vm.State().DelayTimer)
 }
 
 func (suite *Chip8TestSuite) TestRegister0AddToIndex() {
@@ -633,11 +736,30 @@ func (suite *Chip8TestSuite) TestStoreTwoRegistersToMemory() {
 	suite.Equal(uint8(0xFF), suite.vm.Memory[0x20F])
 }
 
+func (suite *Chip8TestSuite) TestStoreIncrementsIndex() {
+	suite.asm.SetIndexRegister(0x300)
+	suite.asm.Store(3)
+
+	suite.executeInstructions()
+
+	suite.Equal(uint16(0x304), suite.vm.indexRegister)
+}
+
+func (suite *Chip8TestSuite) TestLoadLeavesIndex_whenIncrementQuirkDisabled() {
+	suite.vm = NewVM(&suite.mockDisplay, suite.mockRandom, QuirksSuperChip)
+	suite.asm.SetIndexRegister(0x300)
+	suite.asm.Load(3)
+
+	suite.executeInstructions()
+
+	suite.Equal(uint16(0x300), suite.vm.indexRegister)
+}
+
 func (suite *Chip8TestSuite) TestLoadSingleRegisterFromMemory() {
 	suite.asm.SetIndexRegister(0x300)
 	suite.asm.Load(0)
 
-	suite.vm.Load(suite.asm.Assemble())
+	suite.vm.Load(suite.assembled())
 	suite.vm.Memory[0x300] = 0x88
 	suite.vm.Run()
 	suite.Equal(uint8(0x88), suite.vm.registers[0])
@@ -647,7 +769,7 @@ func (suite *Chip8TestSuite) TestLoadMultipleRegistersFromMemory() {
 	suite.asm.SetIndexRegister(0x300)
 	suite.asm.Load(0xF)
 
-	suite.vm.Load(suite.asm.Assemble())
+	suite.vm.Load(suite.assembled())
 
 	suite.vm.Memory[0x300] = 0x88
 	suite.vm.Memory[0x301] = 0x99
@@ -672,7 +794,28 @@ func (suite *Chip8TestSuite) TestJumpWithOffset() {
 	suite.vm.Load(data)
 	suite.vm.registers[0] = 0x10
 	suite.vm.Run()
-	suite.Equal(uint16(0x355), suite.vm.pc)
+	suite.Equal(uint16(0x357), suite.vm.pc)
+}
+
+func (suite *Chip8TestSuite) TestJumpWithOffsetFromVX_whenJumpQuirkEnabled() {
+	suite.vm = NewVM(&suite.mockDisplay, suite.mockRandom, QuirksSuperChip)
+	data := []byte{0xB3, 0x45}
+	suite.vm.Load(data)
+	suite.vm.registers[0] = 0x10
+	suite.vm.registers[3] = 0x20
+	suite.vm.Run()
+	suite.Equal(uint16(0x367), suite.vm.pc)
+}
+
+func (suite *Chip8TestSuite) TestTargetOfJumpWithOffsetRunsOnce() {
+	suite.vm.Load([]byte{0x60, 0x00, 0xB2, 0x06, 0x00, 0x00, 0x71, 0x01})
+
+	for n := 0; n < 4; n++ {
+		suite.vm.Step()
+	}
+
+	suite.Equal(byte(1), suite.vm.registers[1])
+	suite.Equal(uint16(0x20A), suite.vm.pc)
 }
 
 /*
@@ -687,10 +830,8 @@ FX18: Timers
 */
 
 func (suite *Chip8TestSuite) TestSkipIfKeyPressed() {
-	suite.mockDisplay = mockDisplay{false, drawPatternValues{}, KeyboardEvent, 55}
-	suite.mockRandom = MockRandom{55}
-	suite.mockDisplay.SetKey(0xC)
-	suite.vm = NewVM(&suite.mockDisplay, suite.mockRandom)
+	suite.vm.Keypad().Press(0xC)
+	suite.vm.Keypad().Press(0x3)
 
 	suite.Equal(uint16(0x200), suite.vm.pc)
 
@@ -698,7 +839,7 @@ func (suite *Chip8TestSuite) TestSkipIfKeyPressed() {
 	suite.asm.SkipIfKeyPressed(0)
 	suite.asm.SetRegister(0xA, 0x22) // instead this registr should NOT be set
 	suite.asm.SetRegister(0xB, 0x69) // instead this registr should be set
-	suite.vm.Load(suite.asm.Assemble())
+	suite.vm.Load(suite.assembled())
 	suite.vm.Run()
 
 	suite.executeInstructions()
@@ -708,10 +849,8 @@ func (suite *Chip8TestSuite) TestSkipIfKeyPressed() {
 }
 
 func (suite *Chip8TestSuite) TestSkipIfKeyNotPressed() {
-	suite.mockDisplay = mockDisplay{false, drawPatternValues{}, KeyboardEvent, 55}
-	suite.mockRandom = MockRandom{55}
-	suite.mockDisplay.SetKey(0xC)
-	suite.vm = NewVM(&suite.mockDisplay, suite.mockRandom)
+	suite.vm.Keypad().Press(0xC)
+	suite.vm.Keypad().Press(0x3)
 
 	suite.Equal(uint16(0x200), suite.vm.pc)
 
@@ -719,7 +858,7 @@ func (suite *Chip8TestSuite) TestSkipIfKeyNotPressed() {
 	suite.asm.SkipIfKeyNotPressed(0)
 	suite.asm.SetRegister(0xA, 0x22) // This register should NOT be set as it is skipped
 	suite.asm.SetRegister(0xB, 0x69) // instead this registr should be set
-	suite.vm.Load(suite.asm.Assemble())
+	suite.vm.Load(suite.assembled())
 	suite.vm.Run()
 
 	suite.executeInstructions()
@@ -728,6 +867,615 @@ func (suite *Chip8TestSuite) TestSkipIfKeyNotPressed() {
 	suite.Equal(byte(0x69), suite.vm.registers[0xB])
 }
 
+func (suite *Chip8TestSuite) TestStepExecutesOneInstruction() {
+	suite.asm.SetRegister(0, 0x11)
+	suite.asm.SetRegister(1, 0x22)
+	suite.vm.Load(suite.assembled())
+
+	result, err := suite.vm.Step()
+
+	suite.Nil(err)
+	suite.Equal(StepExecuted, result)
+	suite.Equal(byte(0x11), suite.vm.registers[0])
+	suite.Equal(byte(0x00), suite.vm.registers[1])
+	suite.Equal(uint16(0x202), suite.vm.pc)
+}
+
+func (suite *Chip8TestSuite) TestStepReportsHalt() {
+	result, err := suite.vm.Step()
+
+	suite.Nil(err)
+	suite.Equal(StepHalted, result)
+}
+
+func (suite *Chip8TestSuite) TestRunCyclesExecutesNInstructions() {
+	suite.asm.AddToRegister(0, 1)
+	suite.asm.AddToRegister(0, 1)
+	suite.asm.AddToRegister(0, 1)
+	suite.vm.Load(suite.assembled())
+
+	result, err := suite.vm.RunCycles(2)
+
+	suite.Nil(err)
+	suite.Equal(StepExecuted, result)
+	suite.Equal(byte(2), suite.vm.registers[0])
+}
+
+func (suite *Chip8TestSuite) TestRunCyclesStopsAtHalt() {
+	suite.asm.AddToRegister(0, 1)
+	suite.vm.Load(suite.assembled())
+
+	result, _ := suite.vm.RunCycles(10)
+
+	suite.Equal(StepHalted, result)
+	suite.Equal(uint16(0x204), suite.vm.pc)
+}
+
+func (suite *Chip8TestSuite) TestPausedVMDoesNotRunCycles() {
+	suite.asm.AddToRegister(0, 1)
+	suite.vm.Load(suite.assembled())
+	suite.vm.Pause()
+
+	result, _ := suite.vm.RunCycles(10)
+
+	suite.Equal(StepPaused, result)
+	suite.Equal(byte(0), suite.vm.registers[0])
+}
+
+func (suite *Chip8TestSuite) TestStepExecutesWhilePaused() {
+	suite.asm.AddToRegister(0, 1)
+	suite.vm.Load(suite.assembled())
+	suite.vm.Pause()
+
+	result, _ := suite.vm.Step()
+
+	suite.Equal(StepExecuted, result)
+	suite.Equal(byte(1), suite.vm.registers[0])
+}
+
+func (suite *Chip8TestSuite) TestResumeAllowsRunCycles() {
+	suite.asm.AddToRegister(0, 1)
+	suite.vm.Load(suite.assembled())
+	suite.vm.Pause()
+	suite.vm.Resume()
+
+	result, _ := suite.vm.RunCycles(1)
+
+	suite.Equal(StepExecuted, result)
+	suite.False(suite.vm.Paused())
+}
+
+func (suite *Chip8TestSuite) TestRunForStopsAfterDuration() {
+	suite.asm.Jump(0x200)
+	suite.vm.Load(suite.assembled())
+
+	result, err := suite.vm.RunFor(time.Millisecond * 5)
+
+	suite.Nil(err)
+	suite.Equal(StepExecuted, result)
+	suite.Equal(uint16(0x200), suite.vm.pc)
+}
+
+func (suite *Chip8TestSuite) TestRunFrameExecutesInstructionsPerFrame() {
+	suite.vm.SetInstructionsPerFrame(3)
+	for n := 0; n < 5; n++ {
+		suite.asm.AddToRegister(0, 1)
+	}
+	suite.vm.Load(suite.assembled())
+
+	result, err := suite.vm.RunFrame()
+
+	suite.Nil(err)
+	suite.Equal(StepExecuted, result)
+	suite.Equal(byte(3), suite.vm.registers[0])
+}
+
+func (suite *Chip8TestSuite) TestRunFrameTicksDelayTimerOnce() {
+	suite.vm.SetInstructionsPerFrame(4)
+	suite.asm.SetRegister(0, 10)
+	suite.asm.SetDelayTimer(0)
+	suite.asm.Jump(0x204)
+	suite.vm.Load(suite.assembled())
+
+	suite.vm.RunFrame()
+	suite.Equal(byte(9), suite.vm.timers.Delay())
+
+	suite.vm.RunFrame()
+	suite.Equal(byte(8), suite.vm.timers.Delay())
+}
+
+func (suite *Chip8TestSuite) TestDisplayWaitEndsFrame() {
+	suite.asm.Display(0, 0, 1)
+	suite.asm.AddToRegister(1, 1)
+	suite.vm.Load(suite.assembled())
+
+	result, _ := suite.vm.RunFrame()
+	suite.Equal(StepWaiting, result)
+	suite.Equal(byte(0), suite.vm.registers[1])
+
+	suite.vm.RunFrame()
+	suite.Equal(byte(1), suite.vm.registers[1])
+}
+
+func (suite *Chip8TestSuite) TestNoDisplayWait_whenQuirkDisabled() {
+	suite.vm = NewVM(&suite.mockDisplay, suite.mockRandom, QuirksSuperChip)
+	suite.asm.Display(0, 0, 1)
+	suite.asm.AddToRegister(1, 1)
+	suite.vm.Load(suite.assembled())
+
+	suite.vm.RunFrame()
+
+	suite.Equal(byte(1), suite.vm.registers[1])
+}
+
+func (suite *Chip8TestSuite) TestVirtualClockCountsFrames() {
+	clock := NewVirtualClock()
+	suite.vm.SetClock(clock)
+	suite.vm.SetInstructionsPerFrame(1)
+	suite.asm.SetRegister(0, 1)
+	suite.asm.SetRegister(0, 2)
+	suite.asm.SetRegister(0, 3)
+
+	suite.executeInstructions()
+
+	suite.Equal(uint64(3), clock.Frames())
+}
+
+func (suite *Chip8TestSuite) TestGetDelayTimer() {
+	suite.asm.SetRegister(0, 0x20)
+	suite.asm.SetDelayTimer(0)
+	suite.asm.GetDelayTimer(1)
+
+	suite.executeInstructions()
+
+	suite.Equal(byte(0x20), suite.vm.registers[1])
+}
+
+func (suite *Chip8TestSuite) TestSetSoundTimerTurnsBuzzerOn() {
+	suite.vm.SetInstructionsPerFrame(2)
+	suite.asm.SetRegister(0, 2)
+	suite.asm.SetSoundTimer(0)
+	suite.asm.Jump(0x204)
+	suite.vm.Load(suite.assembled())
+
+	suite.False(suite.vm.BuzzerOn())
+	suite.vm.RunFrame()
+	suite.Equal(byte(1), suite.vm.timers.Sound())
+	suite.True(suite.vm.BuzzerOn())
+	suite.vm.RunFrame()
+	suite.False(suite.vm.BuzzerOn())
+}
+
+func (suite *Chip8TestSuite) TestSoundTimerDrivesTone() {
+	audio := &mockAudio{}
+	suite.vm.SetAudio(audio)
+	suite.vm.SetInstructionsPerFrame(2)
+	suite.asm.SetRegister(0, 2)
+	suite.asm.SetSoundTimer(0)
+	suite.asm.Jump(0x204)
+	suite.vm.Load(suite.assembled())
+
+	suite.vm.RunFrame()
+	suite.Equal([]bool{true}, audio.tones)
+
+	suite.vm.RunFrame()
+	suite.vm.RunFrame()
+	suite.Equal([]bool{true, false}, audio.tones)
+}
+
+func (suite *Chip8TestSuite) TestLoadAudioPatternAndPitch() {
+	audio := &mockAudio{}
+	suite.vm.SetAudio(audio)
+	suite.asm.SetIndexRegister(0x300)
+	suite.asm.LoadAudioPattern()
+	suite.asm.SetRegister(0, 112)
+	suite.asm.SetPitch(0)
+	suite.vm.Load(suite.assembled())
+	for n := 0; n < 16; n++ {
+		suite.vm.Memory[0x300+n] = byte(n)
+	}
+
+	suite.vm.Run()
+
+	expected := SoundPattern{Buffer: [16]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}, Pitch: 112}
+	suite.Equal([]SoundPattern{DefaultSoundPattern, {Buffer: expected.Buffer, Pitch: 64}, expected}, audio.patterns)
+}
+
+func (suite *Chip8TestSuite) TestAudioIsRenderedEachFrame() {
+	audio := &mockAudio{}
+	suite.vm.SetAudio(audio)
+	suite.asm.Jump(0x200)
+	suite.vm.Load(suite.assembled())
+
+	suite.vm.RunFrame()
+	suite.vm.RunFrame()
+
+	suite.Equal(2, audio.frames)
+}
+
+func (suite *Chip8TestSuite) TestStopEndsRunFromAnotherGoroutine() {
+	suite.asm.SetRegister(0, 0xFF)
+	suite.asm.SetSoundTimer(0)
+	suite.asm.SetDelayTimer(0)
+	suite.asm.Jump(0x200)
+	suite.vm.Load(suite.assembled())
+
+	done := make(chan bool)
+	go func() {
+		suite.vm.Run()
+		done <- true
+	}()
+
+	for !suite.vm.BuzzerOn() {
+		time.Sleep(time.Millisecond)
+	}
+	suite.vm.Stop()
+	<-done
+}
+
+func (suite *Chip8TestSuite) TestStackOverflowReturnsError() {
+	suite.asm.SetRegister(3, 0x33)
+	suite.asm.Sub(0x202)
+	suite.vm.Load(suite.assembled())
+
+	err := suite.vm.Run()
+
+	vmError, ok := err.(*VMError)
+	suite.True(ok)
+	suite.Equal(StackOverflow, vmError.Kind)
+	suite.Equal(uint16(0x202), vmError.PC)
+	suite.Equal(uint16(0x2202), vmError.Opcode)
+	suite.Equal(byte(0x33), vmError.Registers[3])
+	suite.Equal(uint16(0x202), suite.vm.pc)
+}
+
+func (suite *Chip8TestSuite) TestReturnWithEmptyStackReturnsError() {
+	suite.asm.Return()
+	suite.vm.Load(suite.assembled())
+
+	result, err := suite.vm.Step()
+
+	suite.Equal(StepHalted, result)
+	suite.Equal(StackUnderflow, err.(*VMError).Kind)
+	suite.Equal(uint16(0x200), suite.vm.pc)
+}
+
+func (suite *Chip8TestSuite) TestStoreOutOfBoundsReturnsError() {
+	suite.asm.SetIndexRegister(0xFFE)
+	suite.asm.Store(3)
+	suite.vm.Load(suite.assembled())
+
+	err := suite.vm.Run()
+
+	suite.Equal(MemoryOutOfBounds, err.(*VMError).Kind)
+	suite.Equal(uint16(0xFFE), err.(*VMError).IndexRegister)
+}
+
+func (suite *Chip8TestSuite) TestLoadOutOfBoundsReturnsError() {
+	suite.asm.SetIndexRegister(0xFFF)
+	suite.asm.Load(1)
+	suite.vm.Load(suite.assembled())
+
+	err := suite.vm.Run()
+
+	suite.Equal(MemoryOutOfBounds, err.(*VMError).Kind)
+}
+
+func (suite *Chip8TestSuite) TestBcdOutOfBoundsReturnsError() {
+	suite.asm.SetIndexRegister(0xFFE)
+	suite.asm.BCD(0)
+	suite.vm.Load(suite.assembled())
+
+	err := suite.vm.Run()
+
+	suite.Equal(MemoryOutOfBounds, err.(*VMError).Kind)
+}
+
+func (suite *Chip8TestSuite) TestDrawOutOfBoundsReturnsError() {
+	suite.asm.SetIndexRegister(0xFFC)
+	suite.asm.Display(0, 0, 5)
+	suite.vm.Load(suite.assembled())
+
+	err := suite.vm.Run()
+
+	suite.Equal(MemoryOutOfBounds, err.(*VMError).Kind)
+}
+
+func (suite *Chip8TestSuite) TestUnknownArithmeticOpcodeReturnsError() {
+	suite.vm.Load([]byte{0x81, 0x28})
+
+	err := suite.vm.Run()
+
+	suite.Equal(UnknownOpcode, err.(*VMError).Kind)
+	suite.Equal(uint16(0x8128), err.(*VMError).Opcode)
+}
+
+func (suite *Chip8TestSuite) TestUnknownFurtherOpcodeReturnsError() {
+	suite.vm.Load([]byte{0xF0, 0xFF})
+
+	err := suite.vm.Run()
+
+	suite.Equal(UnknownOpcode, err.(*VMError).Kind)
+	suite.Equal("unknown opcode at 200 executing F0FF", err.Error())
+}
+
+func (suite *Chip8TestSuite) TestPCOutOfRangeReturnsError() {
+	suite.asm.Jump(0xFFF)
+	suite.vm.Load(suite.assembled())
+
+	err := suite.vm.Run()
+
+	suite.Equal(PCOutOfRange, err.(*VMError).Kind)
+	suite.Equal(uint16(0xFFF), err.(*VMError).PC)
+}
+
+func (suite *Chip8TestSuite) TestSkipUnknownOpcode() {
+	suite.vm.SetUnknownOpcodePolicy(SkipUnknownOpcode)
+	suite.vm.Load([]byte{0xF0, 0xFF, 0x81, 0x28, 0x01, 0x23, 0x60, 0x42})
+
+	err := suite.vm.Run()
+
+	suite.Nil(err)
+	suite.Equal(byte(0x42), suite.vm.registers[0])
+}
+
+func (suite *Chip8TestSuite) TestTrapUnknownOpcode() {
+	var trapped []uint16
+	suite.vm.SetUnknownOpcodePolicy(TrapUnknownOpcode)
+	suite.vm.SetTrapHandler(func(vm *VM, opcode uint16) error {
+		trapped = append(trapped, opcode)
+		vm.Memory[0x300] = 0x99
+		return nil
+	})
+	suite.vm.Load([]byte{0xF0, 0xFF, 0x60, 0x42})
+
+	err := suite.vm.Run()
+
+	suite.Nil(err)
+	suite.Equal([]uint16{0xF0FF}, trapped)
+	suite.Equal(byte(0x99), suite.vm.Memory[0x300])
+	suite.Equal(byte(0x42), suite.vm.registers[0])
+}
+
+func (suite *Chip8TestSuite) TestTrapHandlerErrorHaltsVM() {
+	trapError := errors.New("bad opcode")
+	suite.vm.SetUnknownOpcodePolicy(TrapUnknownOpcode)
+	suite.vm.SetTrapHandler(func(vm *VM, opcode uint16) error {
+		return trapError
+	})
+	suite.vm.Load([]byte{0x60, 0x42, 0xE1, 0x00})
+
+	err := suite.vm.Run()
+
+	suite.Equal(trapError, err)
+	suite.Equal(uint16(0x202), suite.vm.pc)
+}
+
+func (suite *Chip8TestSuite) TestTrapWithoutHandlerHalts() {
+	suite.vm.SetUnknownOpcodePolicy(TrapUnknownOpcode)
+	suite.vm.Load([]byte{0xF0, 0xFF})
+
+	err := suite.vm.Run()
+
+	suite.Equal(UnknownOpcode, err.(*VMError).Kind)
+}
+
+func (suite *Chip8TestSuite) TestHighResolutionDrawsAcrossWholeScreen() {
+	suite.asm.HighResolution()
+	suite.asm.SetRegister(0, 100)
+	suite.asm.SetRegister(1, 60)
+	suite.asm.SetIndexRegister(FontMemory)
+	suite.asm.Display(0, 1, 1)
+
+	suite.executeInstructions()
+
+	screen := suite.vm.DisplayBuffer()
+	suite.True(screen.HighResolution())
+	suite.Equal([]byte{1, 1, 1, 1, 0}, screen.Pixels[60][100:105])
+}
+
+func (suite *Chip8TestSuite) TestLowResolutionRestoresScreen() {
+	suite.asm.HighResolution()
+	suite.asm.LowResolution()
+
+	suite.executeInstructions()
+
+	suite.Equal(64, suite.vm.DisplayBuffer().Width())
+}
+
+func (suite *Chip8TestSuite) TestScrollInstructions() {
+	suite.asm.SetIndexRegister(FontMemory)
+	suite.asm.SetRegister(0, 8)
+	suite.asm.Display(0, 1, 1)
+	suite.asm.ScrollDown(3)
+	suite.asm.ScrollRight()
+	suite.asm.ScrollRight()
+	suite.asm.ScrollLeft()
+
+	suite.vm = NewVM(&suite.mockDisplay, suite.mockRandom, QuirksSuperChip)
+	suite.executeInstructions()
+
+	suite.Equal([]byte{0, 1, 1, 1, 1, 0}, suite.vm.DisplayBuffer().Pixels[3][11:17])
+}
+
+func (suite *Chip8TestSuite) TestExitHaltsVM() {
+	suite.asm.SetRegister(0, 1)
+	suite.asm.Exit()
+	suite.asm.SetRegister(0, 2)
+	suite.vm.Load(suite.assembled())
+
+	result, _ := suite.vm.RunCycles(10)
+
+	suite.Equal(StepHalted, result)
+	suite.Equal(byte(1), suite.vm.registers[0])
+	suite.Equal(uint16(0x202), suite.vm.pc)
+}
+
+func (suite *Chip8TestSuite) TestDrawLargeSprite() {
+	suite.vm = NewVM(&suite.mockDisplay, suite.mockRandom, QuirksSuperChip)
+	suite.asm.SetIndexRegister(0x300)
+	suite.asm.Display(0, 0, 0)
+	suite.vm.Load(suite.assembled())
+	suite.vm.Memory[0x300] = 0xFF
+	suite.vm.Memory[0x301] = 0xFF
+	suite.vm.Memory[0x31F] = 0x01
+
+	suite.vm.Run()
+
+	screen := suite.vm.DisplayBuffer()
+	suite.Equal(uint8(1), screen.GetPixelAt(15, 0))
+	suite.Equal(uint8(1), screen.GetPixelAt(15, 15))
+	suite.Equal(uint8(0), screen.GetPixelAt(14, 15))
+	suite.Equal(byte(0), suite.vm.registers[15])
+}
+
+func (suite *Chip8TestSuite) TestIndexPointsToLargeCharacter() {
+	suite.asm.SetRegister(0, 0x2)
+	suite.asm.LargeFontChar(0)
+
+	suite.executeInstructions()
+
+	suite.Equal(uint16(largeFontMemory+20), suite.vm.indexRegister)
+	suite.Equal(byte(0x3E), suite.vm.Memory[suite.vm.indexRegister])
+}
+
+func (suite *Chip8TestSuite) TestStoreAndLoadFlags() {
+	suite.asm.SetRegister(0, 0x11)
+	suite.asm.SetRegister(1, 0x22)
+	suite.asm.SetRegister(2, 0x33)
+	suite.asm.StoreFlags(1)
+	suite.asm.SetRegister(0, 0)
+	suite.asm.SetRegister(1, 0)
+	suite.asm.SetRegister(2, 0)
+	suite.asm.LoadFlags(2)
+
+	suite.executeInstructions()
+
+	suite.Equal(byte(0x11), suite.vm.registers[0])
+	suite.Equal(byte(0x22), suite.vm.registers[1])
+	suite.Equal(byte(0x00), suite.vm.registers[2])
+}
+
+func (suite *Chip8TestSuite) TestXOChipHasExtendedMemory() {
+	suite.vm = NewVM(&suite.mockDisplay, suite.mockRandom, QuirksXOChip)
+	suite.asm.LongSetIndexRegister(0xFF00)
+	suite.asm.SetRegister(0, 0x42)
+	suite.asm.Store(0)
+
+	suite.executeInstructions()
+
+	suite.Equal(0x10000, len(suite.vm.Memory))
+	suite.Equal(byte(0x42), suite.vm.Memory[0xFF00])
+	suite.Equal(uint16(0xFF01), suite.vm.indexRegister)
+	suite.Equal(4096, len(NewVM(&suite.mockDisplay, suite.mockRandom, QuirksCosmacVIP).Memory))
+}
+
+func (suite *Chip8TestSuite) TestSkipJumpsOverLongIndexLoad() {
+	suite.asm.SetRegister(0, 1)
+	suite.asm.SkipIfEqual(0, 1)
+	suite.asm.LongSetIndexRegister(0x1234)
+	suite.asm.SetRegister(1, 2)
+
+	suite.executeInstructions()
+
+	suite.Equal(uint16(0), suite.vm.indexRegister)
+	suite.Equal(byte(2), suite.vm.registers[1])
+}
+
+func (suite *Chip8TestSuite) TestSaveAndLoadRegisterRanges() {
+	suite.asm.SetRegister(2, 0x22)
+	suite.asm.SetRegister(3, 0x33)
+	suite.asm.SetRegister(4, 0x44)
+	suite.asm.SetIndexRegister(0x300)
+	suite.asm.SaveRange(2, 4)
+	suite.asm.SetIndexRegister(0x310)
+	suite.asm.SaveRange(4, 2)
+	suite.asm.SetIndexRegister(0x300)
+	suite.asm.LoadRange(7, 5)
+
+	suite.executeInstructions()
+
+	suite.Equal([]byte{0x22, 0x33, 0x44}, suite.vm.Memory[0x300:0x303])
+	suite.Equal([]byte{0x44, 0x33, 0x22}, suite.vm.Memory[0x310:0x313])
+	suite.Equal([]byte{0x44, 0x33, 0x22}, suite.vm.registers[5:8])
+	suite.Equal(uint16(0x300), suite.vm.indexRegister)
+}
+
+func (suite *Chip8TestSuite) TestDrawToBothPlanes() {
+	suite.vm = NewVM(&suite.mockDisplay, suite.mockRandom, QuirksXOChip)
+	suite.asm.SelectPlanes(3)
+	suite.asm.SetIndexRegister(0x300)
+	suite.asm.Display(0, 0, 1)
+	suite.vm.Load(suite.assembled())
+	suite.vm.Memory[0x300] = 0xF0
+	suite.vm.Memory[0x301] = 0x3C
+
+	suite.vm.Run()
+
+	screen := suite.vm.DisplayBuffer()
+	suite.Equal([]byte{1, 1, 3, 3, 2, 2, 0, 0}, screen.Pixels[0][:8])
+	suite.Equal(byte(0), suite.vm.registers[15])
+}
+
+func (suite *Chip8TestSuite) TestClearScreenOnlyClearsSelectedPlanes() {
+	suite.vm = NewVM(&suite.mockDisplay, suite.mockRandom, QuirksXOChip)
+	suite.asm.SelectPlanes(3)
+	suite.asm.SetIndexRegister(0x300)
+	suite.asm.Display(0, 0, 1)
+	suite.asm.SelectPlanes(1)
+	suite.asm.ClearScreen()
+	suite.vm.Load(suite.assembled())
+	suite.vm.Memory[0x300] = 0x80
+	suite.vm.Memory[0x301] = 0x80
+
+	suite.vm.Run()
+
+	suite.Equal(byte(2), suite.vm.DisplayBuffer().GetPixelAt(0, 0))
+}
+
 func TestChip8TestSuite(t *testing.T) {
 	suite.Run(t, new(Chip8TestSuite))
 }
+
+func benchmarkProgram() *VM {
+	display := mockDisplay{}
+	vm := NewVM(&display, MockRandom{55}, QuirksSuperChip)
+	asm := NewAssembler()
+	asm.SetRegister(0, 1)
+	asm.Or(1, 0)
+	asm.SkipIfEqual(1, 0)
+	asm.SetIndexRegister(0x300)
+	asm.AddToIndex(0)
+	asm.Jump(0x200)
+	code, _ := asm.Assemble()
+	vm.Load(code)
+	return vm
+}
+
+func TestInstructionDispatchDoesNotAllocate(t *testing.T) {
+	vm := benchmarkProgram()
+	allocs := testing.AllocsPerRun(1000, func() {
+		vm.fetchAndProcessInstruction()
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations per instruction, got %v", allocs)
+	}
+}
+
+func BenchmarkFetchAndProcessInstruction(b *testing.B) {
+	vm := benchmarkProgram()
+	b.ReportAllocs()
+	b.ResetTimer()
+	start := time.Now()
+	for n := 0; n < b.N; n++ {
+		vm.fetchAndProcessInstruction()
+	}
+	b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "instructions/s")
+}
+
+func BenchmarkDecode(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		decode(uint16(n))
+	}
+}
@@ -0,0 +1,70 @@
+package chip8
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// WAVSink keeps every sample written to it so that the sound can be checked or saved as a WAV file.
+type WAVSink struct {
+	sampleRate int
+	samples    []int8
+}
+
+func NewWAVSink(sampleRate int) *WAVSink {
+	return &WAVSink{sampleRate: sampleRate}
+}
+
+func (w *WAVSink) WriteSamples(samples []int8) error {
+	w.samples = append(w.samples, samples...)
+	return nil
+}
+
+func (w *WAVSink) Samples() []int8 {
+	return w.samples
+}
+
+// WriteTo writes the samples as an 8 bit mono PCM WAV file.
+func (w *WAVSink) WriteTo(writer io.Writer) (int64, error) {
+	dataSize := uint32(len(w.samples))
+	header := struct {
+		Riff          [4]byte
+		RiffSize      uint32
+		Wave          [4]byte
+		Fmt           [4]byte
+		FmtSize       uint32
+		AudioFormat   uint16
+		Channels      uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+		Data          [4]byte
+		DataSize      uint32
+	}{
+		Riff:          [4]byte{'R', 'I', 'F', 'F'},
+		RiffSize:      36 + dataSize,
+		Wave:          [4]byte{'W', 'A', 'V', 'E'},
+		Fmt:           [4]byte{'f', 'm', 't', ' '},
+		FmtSize:       16,
+		AudioFormat:   1,
+		Channels:      1,
+		SampleRate:    uint32(w.sampleRate),
+		ByteRate:      uint32(w.sampleRate),
+		BlockAlign:    1,
+		BitsPerSample: 8,
+		Data:          [4]byte{'d', 'a', 't', 'a'},
+		DataSize:      dataSize,
+	}
+	if err := binary.Write(writer, binary.LittleEndian, header); err != nil {
+		return 0, err
+	}
+
+	// 8 bit WAV samples are unsigned, centred on 128
+	data := make([]byte, len(w.samples))
+	for n, sample := range w.samples {
+		data[n] = byte(int(sample) + 128)
+	}
+	written, err := writer.Write(data)
+	return int64(binary.Size(header) + written), err
+}
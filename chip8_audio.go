@@ -0,0 +1,47 @@
+package main
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const sampleRate = 44100
+
+// Keep a few frames queued so the device never runs dry, but not so many that the sound lags
+const maxQueuedFrames = 4
+
+// Chip8Audio is an audio sink that queues the rendered samples on an SDL audio device.
+type Chip8Audio struct {
+	device sdl.AudioDeviceID
+	buffer []byte
+}
+
+func (a *Chip8Audio) startUp() error {
+	spec := sdl.AudioSpec{
+		Freq:     sampleRate,
+		Format:   sdl.AUDIO_S8,
+		Channels: 1,
+		Samples:  512,
+	}
+	device, err := sdl.OpenAudioDevice("", false, &spec, nil, 0)
+	if err != nil {
+		return err
+	}
+	a.device = device
+	sdl.PauseAudioDevice(a.device, false)
+	return nil
+}
+
+func (a *Chip8Audio) shutdown() {
+	sdl.CloseAudioDevice(a.device)
+}
+
+func (a *Chip8Audio) WriteSamples(samples []int8) error {
+	if sdl.GetQueuedAudioSize(a.device) > uint32(len(samples)*maxQueuedFrames) {
+		return nil
+	}
+	a.buffer = a.buffer[:0]
+	for _, sample := range samples {
+		a.buffer = append(a.buffer, byte(sample))
+	}
+	return sdl.QueueAudio(a.device, a.buffer)
+}
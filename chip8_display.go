@@ -2,15 +2,24 @@ package main
 
 import (
 	"chip8"
+	"fmt"
 	"github.com/veandco/go-sdl2/sdl"
+	"os"
 )
 
+const windowWidth = 640
+const windowHeight = 320
+
+// palette holds the colour for each combination of the two XO-CHIP bitplanes
+var palette = [4]uint32{0x00000000, 0x00fffff0, 0x00ff6000, 0x00606060}
+
 type Chip8Display struct {
-	window        *sdl.Window
-	keyCode       uint8
-	keyPressed    bool
-	displayBuffer *chip8.DisplayBuffer
-	surface       *sdl.Surface
+	window  *sdl.Window
+	surface *sdl.Surface
+	// hotkeys are actions for the emulator itself, such as saving the state, run when their key is pressed
+	hotkeys map[sdl.Keycode]func()
+	// holdKeys are told when their key is pressed and released, such as the key for rewinding
+	holdKeys map[sdl.Keycode]func(held bool)
 }
 
 func NewChip8Display() *Chip8Display {
@@ -19,20 +28,27 @@ func NewChip8Display() *Chip8Display {
 	return chip8Display
 }
 
-func (k *Chip8Display) GetKey() int {
-	k.keyPressed = false
-	return int(k.keyCode)
+func (d *Chip8Display) SetHotkey(key sdl.Keycode, action func()) {
+	if d.hotkeys == nil {
+		d.hotkeys = make(map[sdl.Keycode]func())
+	}
+	d.hotkeys[key] = action
 }
 
-func (d *Chip8Display) startUp() {
-	d.displayBuffer = chip8.NewDisplayBuffer()
+func (d *Chip8Display) SetHoldKey(key sdl.Keycode, action func(held bool)) {
+	if d.holdKeys == nil {
+		d.holdKeys = make(map[sdl.Keycode]func(held bool))
+	}
+	d.holdKeys[key] = action
+}
 
+func (d *Chip8Display) startUp() {
 	if err := sdl.Init(sdl.INIT_EVERYTHING); err != nil {
 		panic(err)
 	}
 
 	window, err := sdl.CreateWindow("test", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
-		640, 320, sdl.WINDOW_SHOWN)
+		windowWidth, windowHeight, sdl.WINDOW_SHOWN)
 	if err != nil {
 		panic(err)
 	}
@@ -48,54 +64,96 @@ func (d *Chip8Display) shutdown() {
 	sdl.Quit()
 }
 
-func (d *Chip8Display) ClearScreen() {
-	d.displayBuffer.ClearScreen()
-	d.surface.FillRect(nil, 0)
-	d.window.UpdateSurface()
-}
-
-func (d *Chip8Display) DrawSprite(startAddress uint16, heightInPixels byte, x byte, y byte, memory [4096]byte) bool {
-	overflow := d.displayBuffer.DrawSprite(startAddress, heightInPixels, x, y, memory)
+// showCrash reports why the program stopped and leaves the last frame on screen until the window is closed
+func (d *Chip8Display) showCrash(err error) {
+	fmt.Fprintf(os.Stderr, "Program crashed: %s\n", err)
+	if vmError, ok := err.(*chip8.VMError); ok {
+		for n, value := range vmError.Registers {
+			fmt.Fprintf(os.Stderr, "V%X=%02X ", n, value)
+		}
+		fmt.Fprintf(os.Stderr, "I=%03X\n", vmError.IndexRegister)
+	}
 
-	d.writeDisplay()
-	return overflow
+	d.window.SetTitle("Crashed: " + err.Error())
+	for d.PollEvents(new(chip8.Keypad)) != chip8.QuitEvent {
+		sdl.Delay(16)
+	}
 }
 
-func (d *Chip8Display) writeDisplay() {
-	for y := 0; y < 32; y++ {
-		for x := 0; x < 64; x++ {
-			if d.displayBuffer.GetPixelAt(byte(x), byte(y)) == 1 {
-				d.drawPoint(byte(x), byte(y), 0x00fffff0)
-			} else {
-				d.drawPoint(byte(x), byte(y), 0x00000000)
-			}
+func (d *Chip8Display) Render(buffer *chip8.DisplayBuffer) {
+	scale := int32(windowWidth / buffer.Width())
+	for y := 0; y < buffer.Height(); y++ {
+		for x := 0; x < buffer.Width(); x++ {
+			colour := palette[buffer.GetPixelAt(byte(x), byte(y))&3]
+			d.drawPoint(int32(x), int32(y), scale, colour)
 		}
 	}
 
 	d.window.UpdateSurface()
 }
 
-func (d *Chip8Display) drawPoint(x byte, y byte, colour uint32) {
-	rect := sdl.Rect{int32(x) * 10, int32(y) * 10, 10, 10}
+func (d *Chip8Display) drawPoint(x int32, y int32, scale int32, colour uint32) {
+	rect := sdl.Rect{x * scale, y * scale, scale, scale}
 	d.surface.FillRect(&rect, colour)
 }
 
-func (d *Chip8Display) PollEvents() (quit chip8.EventType) {
+func (d *Chip8Display) PollEvents(keypad *chip8.Keypad) chip8.EventType {
+	result := chip8.EventType(chip8.NoEvent)
 	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
 		switch t := event.(type) {
 		case *sdl.QuitEvent:
-			println("Quit")
 			return chip8.QuitEvent
 
 		case *sdl.KeyboardEvent:
-			if t.Type == sdl.KEYUP {
-				println("keyboard %s", t.Keysym.Sym)
-				d.keyCode = chip8.KeyCodeToValue(int(t.Keysym.Sym))
-				println("COSMAC VIP key pressed %s", d.keyCode)
-				d.keyPressed = true
-				return chip8.KeyboardEvent
+			if action, ok := d.hotkeys[t.Keysym.Sym]; ok {
+				if t.Type == sdl.KEYDOWN && t.Repeat == 0 {
+					action()
+				}
+				continue
+			}
+			if action, ok := d.holdKeys[t.Keysym.Sym]; ok {
+				action(t.Type == sdl.KEYDOWN)
+				continue
 			}
+			key, ok := chip8.KeyCodeToValue(int(t.Keysym.Sym))
+			if !ok {
+				continue
+			}
+			if t.Type == sdl.KEYDOWN {
+				keypad.Press(key)
+			} else {
+				keypad.Release(key)
+			}
+			result = chip8.KeyboardEvent
+		}
+	}
+	if d.matchKeyboard(keypad) {
+		result = chip8.KeyboardEvent
+	}
+	return result
+}
+
+// matchKeyboard presses and releases keys so that the keypad matches the keys held on the keyboard,
+// which they might not after loading a state or rewinding. It reports whether anything changed.
+func (d *Chip8Display) matchKeyboard(keypad *chip8.Keypad) bool {
+	var held uint16
+	for scancode, down := range sdl.GetKeyboardState() {
+		if down == 0 {
+			continue
+		}
+		if key, ok := chip8.KeyCodeToValue(int(sdl.GetKeyFromScancode(sdl.Scancode(scancode)))); ok {
+			held |= 1 << key
+		}
+	}
+	if held == keypad.Pressed() {
+		return false
+	}
+	for key := byte(0); key < 16; key++ {
+		if held&(1<<key) != 0 {
+			keypad.Press(key)
+		} else {
+			keypad.Release(key)
 		}
 	}
-	return chip8.NoEvent
+	return true
 }
@@ -0,0 +1,47 @@
+// Command chip8asm assembles a CHIP-8 source file into a ROM that can be run with -rom.
+package main
+
+import (
+	"chip8"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var output = flag.String("o", "", "The filename of the ROM to write, defaults to the source name with a .ch8 extension")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: chip8asm [-o rom.ch8] source.asm")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	sourceFile := flag.Arg(0)
+	if *output == "" {
+		*output = strings.TrimSuffix(sourceFile, filepath.Ext(sourceFile)) + ".ch8"
+	}
+
+	source, err := os.Open(sourceFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer source.Close()
+
+	code, err := chip8.AssembleSourceFile(sourceFile, source)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := ioutil.WriteFile(*output, code, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,64 @@
+// Command chip8dis disassembles a CHIP-8 ROM into source that chip8asm can assemble again.
+package main
+
+import (
+	"bytes"
+	"chip8"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+func main() {
+	var output = flag.String("o", "", "The filename of the source to write, defaults to stdout")
+	var origin = flag.Uint("origin", chip8.ProgramStart, "The address the ROM is loaded at")
+	var dot = flag.String("dot", "", "Write a Graphviz graph instead of source: cfg for the basic blocks or calls for the call graph")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: chip8dis [-o source.asm] [-origin 0x200] [-dot cfg|calls] rom.ch8")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *origin > 0xFFFF {
+		fmt.Fprintln(os.Stderr, "The origin must be a 16 bit address")
+		os.Exit(2)
+	}
+
+	code, err := ioutil.ReadFile(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var source string
+	switch *dot {
+	case "":
+		source = chip8.Disassemble(code, uint16(*origin))
+	case "cfg", "calls":
+		var graph bytes.Buffer
+		analysis := chip8.Analyze(code, uint16(*origin))
+		if *dot == "cfg" {
+			analysis.WriteControlFlowDOT(&graph)
+		} else {
+			analysis.WriteCallGraphDOT(&graph)
+		}
+		source = graph.String()
+	default:
+		fmt.Fprintln(os.Stderr, "Unknown graph", *dot)
+		os.Exit(2)
+	}
+
+	if *output == "" {
+		fmt.Print(source)
+		return
+	}
+	if err := ioutil.WriteFile(*output, []byte(source), 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}